@@ -0,0 +1,284 @@
+// Package main implements logic of the window manager
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/xgb"
+	"github.com/Zamony/wmwm/config"
+	"github.com/Zamony/wmwm/logging"
+	"github.com/Zamony/wmwm/proto"
+)
+
+// Kinds of arguments accepted by actions
+const (
+	argNone = iota
+	argDirection
+	argWorkspace
+	argCommand
+	argName
+	argLayout
+)
+
+var (
+	errQuit    = errors.New("Quit requested")
+	errReload  = errors.New("Reload requested")
+	errRestart = errors.New("Restart requested")
+)
+
+// Action represents named command of the window manager,
+// e.g. "focus left" or "spawn xterm -e top"
+type Action struct {
+	Name string
+	Args []string
+}
+
+type actionSpec struct {
+	arg int
+	run func(action Action, conn *xgb.Conn, manager *WorkspaceManager) error
+}
+
+var actionSpecs = map[string]actionSpec{
+	"quit": {argNone, func(Action, *xgb.Conn, *WorkspaceManager) error {
+		return errQuit
+	}},
+	"reload": {argNone, func(Action, *xgb.Conn, *WorkspaceManager) error {
+		return errReload
+	}},
+	"restart": {argNone, func(Action, *xgb.Conn, *WorkspaceManager) error {
+		return errRestart
+	}},
+	"spawn": {argCommand, func(action Action, _ *xgb.Conn, _ *WorkspaceManager) error {
+		_, err := RunCommand(strings.Join(action.Args, " "))
+		return err
+	}},
+	"terminal": {argNone, func(Action, *xgb.Conn, *WorkspaceManager) error {
+		if _, err := RunCommand(config.TerminalCmd()); err != nil {
+			logging.Error("Terminal launch failed")
+		}
+		return nil
+	}},
+	"launcher": {argNone, func(Action, *xgb.Conn, *WorkspaceManager) error {
+		if _, err := RunCommand(config.LauncherCmd()); err != nil {
+			logging.Error("Application launcher failed")
+		}
+		return nil
+	}},
+	"lock": {argNone, func(Action, *xgb.Conn, *WorkspaceManager) error {
+		if _, err := RunCommand(config.LockerCmd()); err != nil {
+			logging.Error("Locker launch failed")
+		}
+		return nil
+	}},
+	"close": {argNone, func(_ Action, conn *xgb.Conn, manager *WorkspaceManager) error {
+		win := NewWindow(0, manager.Mailbox(), conn)
+		win.SendClose(manager.Curr())
+		return nil
+	}},
+	"fullscreen": {argNone, func(_ Action, conn *xgb.Conn, manager *WorkspaceManager) error {
+		win := NewWindow(0, manager.Mailbox(), conn)
+		win.SendFullscreen(manager.Curr())
+		return nil
+	}},
+	"toggle-floating": {argNone, func(_ Action, conn *xgb.Conn, manager *WorkspaceManager) error {
+		win := NewWindow(0, manager.Mailbox(), conn)
+		win.SendToggleFloating(manager.Curr())
+		return nil
+	}},
+	"focus-next": {argNone, func(_ Action, conn *xgb.Conn, manager *WorkspaceManager) error {
+		win := NewWindow(0, manager.Mailbox(), conn)
+		win.SendFocusNext(manager.Curr())
+		return nil
+	}},
+	"new-column": {argNone, func(_ Action, conn *xgb.Conn, manager *WorkspaceManager) error {
+		win := NewWindow(0, manager.Mailbox(), conn)
+		win.SendNewColumn(manager.Curr())
+		return nil
+	}},
+	"merge-column": {argNone, func(_ Action, conn *xgb.Conn, manager *WorkspaceManager) error {
+		win := NewWindow(0, manager.Mailbox(), conn)
+		win.SendMergeColumn(manager.Curr())
+		return nil
+	}},
+	"equalize": {argNone, func(_ Action, conn *xgb.Conn, manager *WorkspaceManager) error {
+		win := NewWindow(0, manager.Mailbox(), conn)
+		win.SendEqualize(manager.Curr())
+		return nil
+	}},
+	"layout": {argLayout, func(action Action, conn *xgb.Conn, manager *WorkspaceManager) error {
+		win := NewWindow(0, manager.Mailbox(), conn)
+		win.SendLayout(manager.Curr(), action.Args[0])
+		return nil
+	}},
+	"cycle-layout": {argNone, func(_ Action, conn *xgb.Conn, manager *WorkspaceManager) error {
+		win := NewWindow(0, manager.Mailbox(), conn)
+		win.SendLayout(manager.Curr(), "")
+		return nil
+	}},
+	"workspace": {argWorkspace, func(action Action, conn *xgb.Conn, manager *WorkspaceManager) error {
+		switchWorkspace(action.Workspace(), conn, manager)
+		return nil
+	}},
+	"move-to-workspace": {argWorkspace, func(action Action, conn *xgb.Conn, manager *WorkspaceManager) error {
+		moveToWorkspace(action.Workspace(), conn, manager)
+		return nil
+	}},
+	"save-layout": {argName, func(action Action, conn *xgb.Conn, manager *WorkspaceManager) error {
+		return saveLayout(action.Args[0], conn, manager)
+	}},
+	"restore-layout": {argName, func(action Action, conn *xgb.Conn, manager *WorkspaceManager) error {
+		return restoreLayout(action.Args[0], conn, manager)
+	}},
+	"focus": {argDirection, func(action Action, conn *xgb.Conn, manager *WorkspaceManager) error {
+		win := NewWindow(0, manager.Mailbox(), conn)
+		switch action.Args[0] {
+		case "left":
+			win.SendFocusLeft(manager.Curr())
+		case "right":
+			win.SendFocusRight(manager.Curr())
+		case "up":
+			win.SendFocusUp(manager.Curr())
+		case "down":
+			win.SendFocusDown(manager.Curr())
+		}
+		return nil
+	}},
+	"move": {argDirection, func(action Action, conn *xgb.Conn, manager *WorkspaceManager) error {
+		win := NewWindow(0, manager.Mailbox(), conn)
+		switch action.Args[0] {
+		case "left":
+			win.SendMoveLeft(manager.Curr())
+		case "right":
+			win.SendMoveRight(manager.Curr())
+		case "up":
+			win.SendMoveUp(manager.Curr())
+		case "down":
+			win.SendMoveDown(manager.Curr())
+		}
+		return nil
+	}},
+	"resize": {argDirection, func(action Action, conn *xgb.Conn, manager *WorkspaceManager) error {
+		win := NewWindow(0, manager.Mailbox(), conn)
+		switch action.Args[0] {
+		case "left":
+			win.SendResizeLeft(manager.Curr())
+		case "right":
+			win.SendResizeRight(manager.Curr())
+		case "up":
+			win.SendResizeUp(manager.Curr())
+		case "down":
+			win.SendResizeDown(manager.Curr())
+		}
+		return nil
+	}},
+}
+
+// ParseAction parses action name and checks its arguments
+func ParseAction(s string) (Action, error) {
+	fields := strings.Fields(s)
+	if len(fields) < 1 {
+		return Action{}, errors.New("Empty action")
+	}
+
+	action := Action{fields[0], fields[1:]}
+	spec, ok := actionSpecs[action.Name]
+	if !ok {
+		return action, fmt.Errorf("Unknown action %q", action.Name)
+	}
+
+	switch spec.arg {
+	case argNone:
+		if len(action.Args) > 0 {
+			return action, fmt.Errorf("Action %q takes no arguments", action.Name)
+		}
+	case argDirection:
+		if len(action.Args) != 1 || !isDirection(action.Args[0]) {
+			return action, fmt.Errorf(
+				"Action %q requires one of left, right, up, down", action.Name,
+			)
+		}
+	case argWorkspace:
+		if len(action.Args) != 1 || action.Workspace() == 0 {
+			return action, fmt.Errorf(
+				"Action %q requires workspace number 1..%d", action.Name, MaxWorkspaces,
+			)
+		}
+	case argCommand:
+		if len(action.Args) < 1 {
+			return action, fmt.Errorf("Action %q requires a command", action.Name)
+		}
+	case argName:
+		if len(action.Args) != 1 {
+			return action, fmt.Errorf("Action %q requires a name", action.Name)
+		}
+	case argLayout:
+		if len(action.Args) != 1 {
+			return action, fmt.Errorf("Action %q requires a layout name", action.Name)
+		}
+		if _, ok := LayoutByName(action.Args[0]); !ok {
+			return action, fmt.Errorf("Unknown layout %q", action.Args[0])
+		}
+	}
+
+	return action, nil
+}
+
+// Run performs the action. It returns errQuit, errRestart or errReload
+// when the event loop should stop or reload configuration
+func (action Action) Run(conn *xgb.Conn, manager *WorkspaceManager) error {
+	return actionSpecs[action.Name].run(action, conn, manager)
+}
+
+// Workspace returns workspace number given as the argument of action
+// or zero if there is no valid one
+func (action Action) Workspace() uint32 {
+	if len(action.Args) < 1 {
+		return 0
+	}
+	id, err := strconv.ParseUint(action.Args[0], 10, 32)
+	if err != nil || id < 1 || id > MaxWorkspaces {
+		return 0
+	}
+	return uint32(id)
+}
+
+// String returns textual representation of the action
+func (action Action) String() string {
+	return strings.Join(append([]string{action.Name}, action.Args...), " ")
+}
+
+func isDirection(s string) bool {
+	return s == "left" || s == "right" || s == "up" || s == "down"
+}
+
+// switchWorkspace makes workspace with the specified id active.
+// Workspace shown on another monitor gets focus, hidden workspace
+// is shown on the focused monitor instead of the current one
+func switchWorkspace(id uint32, conn *xgb.Conn, manager *WorkspaceManager) {
+	if manager.Curr() == id || id < 1 || id > manager.Workspaces() {
+		return
+	}
+
+	win := NewWindow(0, manager.Mailbox(), conn)
+	if !manager.Visible(id) {
+		win.SendDeactivate(manager.Curr())
+		win.SendReload(id, manager.Screen(manager.Curr()))
+	}
+	win.SendActivate(id)
+	manager.SetCurr(id)
+	publishWorkArea(conn, manager)
+}
+
+// moveToWorkspace moves focused window of the current
+// workspace to the workspace with the specified id
+func moveToWorkspace(id uint32, conn *xgb.Conn, manager *WorkspaceManager) {
+	if manager.Curr() == id || id > manager.Workspaces() {
+		return
+	}
+
+	win := NewWindow(manager.Curr(), manager.Mailbox(), conn)
+	win.SendReattachWith(id, proto.DetachOptions{Show: manager.Visible(id)})
+}
@@ -0,0 +1,63 @@
+// Package main implements logic of the window manager
+package main
+
+import (
+	"fmt"
+
+	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/xproto"
+	"github.com/Zamony/wmwm/config"
+	"github.com/Zamony/wmwm/kbrd"
+	"github.com/Zamony/wmwm/xutil"
+)
+
+// Bindings maps grabbed keys combinations to actions
+type Bindings struct {
+	actions map[xutil.Shortcut]Action
+}
+
+// Load reads key bindings from the configuration and grabs them.
+// Bindings stay unchanged if configuration contains invalid action
+// or grabbing fails
+func (bindings *Bindings) Load(conn *xgb.Conn, xroot xproto.ScreenInfo) error {
+	keymap, err := kbrd.Mapping(conn)
+	if err != nil {
+		return err
+	}
+
+	actions := make(map[xutil.Shortcut]Action)
+	shortcuts := make([]xutil.Shortcut, 0)
+	for _, binding := range config.Bindings() {
+		action, err := ParseAction(binding.Action)
+		if err != nil {
+			return fmt.Errorf("Key binding %s: %v", binding.Keys, err)
+		}
+
+		for _, keycode := range kbrd.Keycodes(keymap, binding.Keysym) {
+			shortcut := xutil.Shortcut{
+				Modifiers: binding.Modifiers, Keycode: keycode,
+			}
+			actions[shortcut] = action
+			shortcuts = append(shortcuts, shortcut)
+		}
+	}
+
+	previous := make([]xutil.Shortcut, 0, len(bindings.actions))
+	for shortcut := range bindings.actions {
+		previous = append(previous, shortcut)
+	}
+	if err := xutil.GrabShortcuts(conn, xroot, shortcuts, previous); err != nil {
+		return err
+	}
+	bindings.actions = actions
+	return nil
+}
+
+// Action returns action bound to the pressed keys combination
+func (bindings *Bindings) Action(key xproto.KeyPressEvent) (Action, bool) {
+	shortcut := xutil.Shortcut{
+		Modifiers: key.State & xutil.ModifiersMask, Keycode: key.Detail,
+	}
+	action, ok := bindings.actions[shortcut]
+	return action, ok
+}
@@ -0,0 +1,134 @@
+// Package main implements logic of the window manager
+package main
+
+import "sync"
+
+// clients keeps track of the windows managed by workspaces
+var clients = NewClientList()
+
+// ClientList maps managed windows to their workspaces and keeps
+// them in order of mapping and in stacking order. Workspaces
+// update it, the event loop reads it
+type ClientList struct {
+	mu       sync.Mutex
+	owners   map[uint32]uint32
+	order    []uint32
+	stacking []uint32
+	active   uint32
+	onChange func(order, stacking []uint32, active uint32)
+	// notifying serializes calls of the change handler,
+	// so that the last call reports the latest state
+	notifying sync.Mutex
+}
+
+// NewClientList creates instance of ClientList
+func NewClientList() *ClientList {
+	return &ClientList{owners: make(map[uint32]uint32)}
+}
+
+// OnChange sets function called on every change of the list,
+// e.g. to update EWMH properties of the root window
+func (list *ClientList) OnChange(fn func(order, stacking []uint32, active uint32)) {
+	list.mu.Lock()
+	list.onChange = fn
+	list.mu.Unlock()
+	list.changed()
+}
+
+// Add records that the window belongs to the workspace
+func (list *ClientList) Add(wid, workspace uint32) {
+	list.mu.Lock()
+	if _, ok := list.owners[wid]; !ok {
+		list.order = append(list.order, wid)
+		list.stacking = append(list.stacking, wid)
+	}
+	list.owners[wid] = workspace
+	list.mu.Unlock()
+	list.changed()
+}
+
+// Remove forgets the window if it belongs to the workspace.
+// Window moved to another workspace meanwhile stays in the list
+func (list *ClientList) Remove(wid, workspace uint32) {
+	list.mu.Lock()
+	if owner, ok := list.owners[wid]; !ok || owner != workspace {
+		list.mu.Unlock()
+		return
+	}
+	delete(list.owners, wid)
+	list.order = without(list.order, wid)
+	list.stacking = without(list.stacking, wid)
+	if list.active == wid {
+		list.active = 0
+	}
+	list.mu.Unlock()
+	list.changed()
+}
+
+// Raise moves the window to the top of stacking order
+func (list *ClientList) Raise(wid uint32) {
+	list.mu.Lock()
+	_, ok := list.owners[wid]
+	if ok {
+		list.stacking = append(without(list.stacking, wid), wid)
+	}
+	list.mu.Unlock()
+	if ok {
+		list.changed()
+	}
+}
+
+// SetActive records the focused window, zero means there is no one
+func (list *ClientList) SetActive(wid uint32) {
+	list.mu.Lock()
+	changed := list.active != wid
+	list.active = wid
+	list.mu.Unlock()
+	if changed {
+		list.changed()
+	}
+}
+
+// Owner returns workspace the window belongs to
+func (list *ClientList) Owner(wid uint32) (uint32, bool) {
+	list.mu.Lock()
+	defer list.mu.Unlock()
+	workspace, ok := list.owners[wid]
+	return workspace, ok
+}
+
+// Active returns the focused window
+func (list *ClientList) Active() uint32 {
+	list.mu.Lock()
+	defer list.mu.Unlock()
+	return list.active
+}
+
+// changed calls the change handler with copy of the current state.
+// The lock must not be held, so that the handler doesn't block
+// readers of the list while it talks to the X server
+func (list *ClientList) changed() {
+	list.notifying.Lock()
+	defer list.notifying.Unlock()
+
+	list.mu.Lock()
+	fn := list.onChange
+	order := append([]uint32(nil), list.order...)
+	stacking := append([]uint32(nil), list.stacking...)
+	active := list.active
+	list.mu.Unlock()
+
+	if fn != nil {
+		fn(order, stacking, active)
+	}
+}
+
+func without(wids []uint32, wid uint32) []uint32 {
+	result := make([]uint32, 0, len(wids))
+	for _, w := range wids {
+		if w != wid {
+			result = append(result, w)
+		}
+	}
+	return result
+}
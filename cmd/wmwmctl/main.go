@@ -0,0 +1,77 @@
+// Command wmwmctl sends commands to the running window manager
+// through its control socket
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Zamony/wmwm/ipc"
+)
+
+const usage = `Usage: wmwmctl <command> [arguments]
+
+Commands are the same as actions of key bindings, e.g.
+  wmwmctl focus left
+  wmwmctl move-to-workspace 3
+  wmwmctl workspace 2
+  wmwmctl fullscreen
+  wmwmctl reload
+  wmwmctl restart
+  wmwmctl save-layout dev
+  wmwmctl restore-layout dev
+  wmwmctl quit
+
+Queries:
+  wmwmctl get-tree   prints JSON describing workspaces and windows
+
+Events:
+  wmwmctl subscribe [event...]   prints one JSON event per line,
+      events are workspace, attach, remove, focus, layout and title
+`
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if flag.Arg(0) == "subscribe" {
+		subscribe(flag.Args()[1:])
+		return
+	}
+
+	response, err := ipc.Send(strings.Join(flag.Args(), " "))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "wmwmctl:", err)
+		os.Exit(1)
+	}
+	if len(response.Data) > 0 {
+		fmt.Println(string(response.Data))
+	}
+}
+
+func subscribe(names []string) {
+	sub, err := ipc.Subscribe(names...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "wmwmctl:", err)
+		os.Exit(1)
+	}
+	defer sub.Close()
+
+	encoder := json.NewEncoder(os.Stdout)
+	for {
+		event, err := sub.Next()
+		if err != nil {
+			return
+		}
+		encoder.Encode(event)
+	}
+}
@@ -3,26 +3,28 @@ package main
 
 import (
 	"errors"
+	"math"
 
+	"github.com/Zamony/wmwm/ipc"
 	"github.com/Zamony/wmwm/logging"
 	"github.com/Zamony/wmwm/xutil"
 )
 
-// Column represent group of windows having
-// same height and position at x-axis
+// Column represent group of windows sharing
+// its height according to their weights
 type Column struct {
-	width      int
-	x          int
-	windows    []*Window
-	screen     xutil.Screen
-	fullscreen bool
+	width   int
+	x       int
+	windows []*Window
+	screen  xutil.Screen
+	// ratio is a share of the workspace width taken by the column
+	ratio float64
 }
 
 // NewColumn creates instance of Column
 func NewColumn(screen xutil.Screen) *Column {
 	return &Column{
-		screen.Width(), screen.XOffset(), nil,
-		screen, false,
+		screen.Width(), screen.XOffset(), nil, screen, 1,
 	}
 }
 
@@ -55,55 +57,94 @@ func (column *Column) Swap(i, j int) error {
 	return errors.New("Swapping values: index out of range")
 }
 
-// Reshape reshapes changes windows sizes in the column
-// in such way that the have the same height and position on x-axis
-func (column *Column) Reshape() {
+// Reshape changes sizes of the windows in the column in such way
+// that they have the same position on x-axis and divide the height
+// of the work area according to their weights. Windows whose minimum
+// size doesn't fit the slot are left intact and returned. Fullscreen
+// windows keep their slots, but aren't placed
+func (column *Column) Reshape() []*Window {
+	_, top, _, height := column.screen.WorkArea()
+	return column.Place(Rect{column.x, top, column.width, height})
+}
+
+// Place moves the column to the area and divides its height
+// between the windows the same way as Reshape does
+func (column *Column) Place(area Rect) []*Window {
+	column.x, column.width = area.X, area.Width
 	n := len(column.windows)
 	if n < 1 {
-		return
+		return nil
 	}
 
-	paddingT := column.screen.PaddingTop()
-	paddingB := column.screen.PaddingBottom()
-	if column.fullscreen {
-		paddingT = 0
-		paddingB = 0
+	sum := column.weights()
+	offsety := area.Y
+	var unfit []*Window
+	for i, win := range column.windows {
+		h := int(math.Round(float64(area.Height) * win.Weight() / sum))
+		if i == n-1 {
+			h = area.Height + area.Y - offsety
+		}
+		switch {
+		case win.IsFullscreen():
+		case win.Fits(column.width, h):
+			win.Place(column.x, offsety, column.width, h)
+		default:
+			unfit = append(unfit, win)
+		}
+		offsety += h
 	}
+	return unfit
+}
 
-	height := column.screen.Height() - (paddingT + paddingB)
-	h := height / n
-	offsety := paddingT
-	for i := 0; i < n-1; i++ {
-		win := column.windows[i]
-		win.SetY(offsety)
-		win.SetX(column.x)
-		win.SetHeight(h)
-		win.SetWidth(column.width)
-		offsety += h
+// Grow changes share of the column height taken by the window
+// by the delta, other windows shrink or grow proportionally.
+// No window gets less than the minimum share
+func (column *Column) Grow(idx int, delta, min float64) {
+	n := len(column.windows)
+	if idx < 0 || idx >= n || n < 2 {
+		return
 	}
 
-	win := column.windows[n-1]
-	win.SetY(offsety)
-	win.SetX(column.x)
-	win.SetHeight(height + paddingT - offsety)
-	win.SetWidth(column.width)
-}
+	sum := column.weights()
+	win := column.windows[idx]
+	rest := sum - win.Weight()
+	smallest := math.Inf(1)
+	for i, other := range column.windows {
+		if i != idx {
+			smallest = math.Min(smallest, other.Weight())
+		}
+	}
 
-// HasPadding checks whether column has padding
-func (column *Column) HasPadding() bool {
-	return !column.fullscreen
+	// Other windows are scaled by (1 - share) / (1 - previous share)
+	share := win.Weight() / sum
+	limit := 1 - min*rest/smallest
+	if limit < min {
+		return
+	}
+	share = math.Max(min, math.Min(limit, share+delta))
+	scale := (1 - share) * sum / rest
+	for i, other := range column.windows {
+		if i != idx {
+			other.SetWeight(other.Weight() * scale)
+		}
+	}
+	win.SetWeight(share * sum)
 }
 
-// AddPadding adds padding to the column
-func (column *Column) AddPadding() {
-	column.fullscreen = false
-	column.Reshape()
+// Equalize makes all windows of the column equally tall
+func (column *Column) Equalize() {
+	for _, win := range column.windows {
+		win.SetWeight(1)
+	}
 }
 
-// RemovePadding removes column's padding
-func (column *Column) RemovePadding() {
-	column.fullscreen = true
-	column.Reshape()
+// weights returns sum of the window weights
+func (column *Column) weights() float64 {
+	var sum float64
+	for _, win := range column.windows {
+		sum += win.Weight()
+	}
+	return sum
 }
 
 // IndexById returns index of window by its id
@@ -131,34 +172,49 @@ func (column *Column) WindowByIndex(idx int) *Window {
 
 // SetX sets column's x-coordinate
 func (column *Column) SetX(x int) int {
-	column.x = column.screen.XOffset() + x
+	left, _, _, _ := column.screen.WorkArea()
+	column.x = left + x
 	return column.x
 }
 
-// SetWidth100 sets column width to 100%
-func (column *Column) SetWidth100() int {
-	column.width = column.screen.Width()
-	return column.width
+// SetWidth sets column width
+func (column *Column) SetWidth(width int) {
+	column.width = width
 }
 
-// SetWidth50 sets column width to 50%
-func (column *Column) SetWidth50() int {
-	column.width = column.screen.Width() / 2
-	return column.width
+// Ratio returns share of the workspace width taken by the column
+func (column *Column) Ratio() float64 {
+	return column.ratio
 }
 
-// SetWidth65 sets column width to 65%
-func (column *Column) SetWidth65() int {
-	w := float32(column.screen.Width())
-	column.width = int(w * float32(0.65))
-	return column.width
+// SetRatio sets share of the workspace width taken by the column
+func (column *Column) SetRatio(ratio float64) {
+	column.ratio = ratio
 }
 
-// SetWidth35 sets column width to 35%
-func (column *Column) SetWidth35() int {
-	w := column.SetWidth65()
-	column.width = column.screen.Width() - w
-	return column.width
+// Screen returns screen the column is placed on
+func (column *Column) Screen() xutil.Screen {
+	return column.screen
+}
+
+// SetScreen changes screen the column is placed on
+func (column *Column) SetScreen(screen xutil.Screen) {
+	column.screen = screen
+}
+
+// State returns description of the column and its windows
+func (column *Column) State(position string, focus uint32) ipc.ColumnState {
+	state := ipc.ColumnState{
+		Position: position,
+		X:        column.x,
+		Width:    column.width,
+		Ratio:    column.ratio,
+		Windows:  make([]ipc.WindowState, 0, len(column.windows)),
+	}
+	for _, win := range column.windows {
+		state.Windows = append(state.Windows, win.State(win.Id() == focus))
+	}
+	return state
 }
 
 // LogStatus logs column's information for debugging purposes
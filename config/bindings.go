@@ -0,0 +1,135 @@
+// Package config parses command line arguments
+// and provides access to them
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/BurntSushi/xgb/xproto"
+	"github.com/Zamony/wmwm/kbrd"
+)
+
+// UnboundAction is used to remove default key binding
+const UnboundAction = "none"
+
+// Binding represents keys combination bound to an action
+type Binding struct {
+	Keys      string
+	Modifiers uint16
+	Keysym    xproto.Keysym
+	Action    string
+}
+
+// defaultBindings are used unless overridden in [bindings] section
+var defaultBindings = workspaceBindings([]Entry{
+	{Key: "Control+Mod1+BackSpace", Value: "quit"},
+	{Key: "Mod4+Shift+r", Value: "reload"},
+	{Key: "Mod4+Control+r", Value: "restart"},
+	{Key: "Mod4+t", Value: "terminal"},
+	{Key: "Mod4+grave", Value: "launcher"},
+	{Key: "Mod4+l", Value: "lock"},
+	{Key: "Mod4+q", Value: "close"},
+	{Key: "Mod4+f", Value: "fullscreen"},
+	{Key: "Mod4+space", Value: "toggle-floating"},
+	{Key: "Mod4+Shift+space", Value: "cycle-layout"},
+	{Key: "Mod4+Tab", Value: "focus-next"},
+	{Key: "Mod4+n", Value: "new-column"},
+	{Key: "Mod4+m", Value: "merge-column"},
+	{Key: "Mod4+Left", Value: "focus left"},
+	{Key: "Mod4+Right", Value: "focus right"},
+	{Key: "Mod4+Up", Value: "focus up"},
+	{Key: "Mod4+Down", Value: "focus down"},
+	{Key: "Mod4+Mod1+Left", Value: "move left"},
+	{Key: "Mod4+Mod1+Right", Value: "move right"},
+	{Key: "Mod4+Mod1+Up", Value: "move up"},
+	{Key: "Mod4+Mod1+Down", Value: "move down"},
+	{Key: "Mod4+Control+Left", Value: "resize left"},
+	{Key: "Mod4+Control+Right", Value: "resize right"},
+	{Key: "Mod4+Control+Up", Value: "resize up"},
+	{Key: "Mod4+Control+Down", Value: "resize down"},
+	{Key: "Mod4+Control+e", Value: "equalize"},
+})
+
+// workspaceBindings adds bindings of F1..F9 keys to the entries
+func workspaceBindings(entries []Entry) []Entry {
+	for i := 1; i <= 9; i++ {
+		entries = append(entries,
+			Entry{Key: fmt.Sprintf("F%d", i), Value: fmt.Sprintf("workspace %d", i)},
+			Entry{Key: fmt.Sprintf("Mod4+F%d", i), Value: fmt.Sprintf("move-to-workspace %d", i)},
+		)
+	}
+	return entries
+}
+
+// Bindings returns key bindings: the default ones
+// overridden by [bindings] section of the configuration file
+func Bindings() []Binding {
+	return get().bindings
+}
+
+// ignoredModifiers are Lock and NumLock (Mod2),
+// shortcuts are matched regardless of their state
+const ignoredModifiers = xproto.ModMaskLock | xproto.ModMask2
+
+// ParseKeys parses keys combination like "Mod4+Shift+Return".
+// Lock and Mod2 modifiers are rejected since they are ignored
+func ParseKeys(keys string) (uint16, xproto.Keysym, error) {
+	names := strings.Split(keys, "+")
+	var mods uint16
+	for _, name := range names[:len(names)-1] {
+		mask, ok := kbrd.Modifier(strings.TrimSpace(name))
+		if !ok {
+			return 0, 0, fmt.Errorf("unknown modifier %q", name)
+		}
+		if mask&ignoredModifiers != 0 {
+			return 0, 0, fmt.Errorf("modifier %q is ignored in key bindings", name)
+		}
+		mods |= mask
+	}
+
+	name := strings.TrimSpace(names[len(names)-1])
+	sym, ok := kbrd.Keysym(name)
+	if !ok {
+		return 0, 0, fmt.Errorf("unknown key %q", name)
+	}
+	return mods, sym, nil
+}
+
+// parseBindings applies entries of [bindings] section to the default bindings
+func parseBindings(entries []Entry) ([]Binding, error) {
+	all := make([]Entry, 0, len(defaultBindings)+len(entries))
+	all = append(append(all, defaultBindings...), entries...)
+
+	var bindings []Binding
+	for _, entry := range all {
+		mods, sym, err := ParseKeys(entry.Key)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", entry.Line, err)
+		}
+		if entry.Value == "" {
+			return nil, fmt.Errorf("line %d: empty action", entry.Line)
+		}
+
+		binding := Binding{entry.Key, mods, sym, entry.Value}
+		replaced := false
+		for i := range bindings {
+			if bindings[i].Modifiers == mods && bindings[i].Keysym == sym {
+				bindings[i] = binding
+				replaced = true
+			}
+		}
+		if !replaced {
+			bindings = append(bindings, binding)
+		}
+	}
+
+	n := 0
+	for _, binding := range bindings {
+		if binding.Action != UnboundAction {
+			bindings[n] = binding
+			n++
+		}
+	}
+	return bindings[:n], nil
+}
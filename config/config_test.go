@@ -0,0 +1,195 @@
+package config
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/xgb/xproto"
+	"github.com/Zamony/wmwm/kbrd"
+)
+
+func TestParseFileSections(t *testing.T) {
+	sections, err := parseFile(strings.NewReader(`
+# comment
+term = urxvt -e tmux
+exec = tint2
+
+[bindings]
+Mod4+Return = spawn xterm
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sections) != 2 {
+		t.Fatal("Expected 2 sections, got", len(sections))
+	}
+	if e := sections[0].Entries[0]; e.Key != "term" || e.Value != "urxvt -e tmux" {
+		t.Error("Wrong entry", e)
+	}
+	if sections[1].Name != "bindings" || sections[1].Entries[0].Line != 7 {
+		t.Error("Wrong section", sections[1])
+	}
+}
+
+func TestParseFileInvalid(t *testing.T) {
+	if _, err := parseFile(strings.NewReader("term")); err == nil {
+		t.Error("Line without value accepted")
+	}
+	if _, err := parseFile(strings.NewReader("[bindings")); err == nil {
+		t.Error("Unterminated section header accepted")
+	}
+}
+
+func TestLoadArgsOverrideFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wmwm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config")
+	content := "term = urxvt\nborder-width = 3\nexec = tint2\nexec = nm-applet\n"
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	args := []string{"-config", path, "-border-width", "5"}
+	if err := load(args, flag.ContinueOnError); err != nil {
+		t.Fatal(err)
+	}
+	if TerminalCmd() != "urxvt" {
+		t.Error("Option from file ignored")
+	}
+	if BorderWidth() != 5 {
+		t.Error("Argument doesn't override file")
+	}
+	if len(Commands()) != 2 {
+		t.Error("Expected 2 commands, got", Commands())
+	}
+}
+
+func TestLoadUnknownOption(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wmwm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config")
+	if err := ioutil.WriteFile(path, []byte("colour = 0x0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	previous := get()
+	if err := load([]string{"-config", path}, flag.ContinueOnError); err == nil {
+		t.Error("Unknown option accepted")
+	}
+	if get() != previous {
+		t.Error("Failed load changed configuration")
+	}
+}
+
+func TestParseBindingsOverride(t *testing.T) {
+	bindings, err := parseBindings([]Entry{
+		{Key: "Mod4+q", Value: "none", Line: 1},
+		{Key: "Win+Shift+q", Value: "close", Line: 2},
+		{Key: "Mod4+t", Value: "spawn urxvt", Line: 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// One binding is added and one is removed
+	if len(bindings) != len(defaultBindings) {
+		t.Error("Unexpected number of bindings", len(bindings))
+	}
+	for _, b := range bindings {
+		if b.Keys == "Mod4+q" {
+			t.Error("Unbound key is still bound")
+		}
+		if b.Keys == "Mod4+t" && b.Action != "spawn urxvt" {
+			t.Error("Default binding isn't overridden")
+		}
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	mods, sym, err := ParseKeys("Mod4+Shift+Return")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mods != xproto.ModMask4|xproto.ModMaskShift || sym != kbrd.XK_Return {
+		t.Error("Wrong keys combination", mods, sym)
+	}
+	if _, _, err := ParseKeys("Hyper+x"); err == nil {
+		t.Error("Unknown modifier accepted")
+	}
+	if _, _, err := ParseKeys("Mod4+Enter"); err == nil {
+		t.Error("Unknown key accepted")
+	}
+	for _, keys := range []string{"Lock+x", "Mod4+Mod2+x"} {
+		if _, _, err := ParseKeys(keys); err == nil {
+			t.Errorf("Ignored modifier accepted in %q", keys)
+		}
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	sections, err := parseFile(strings.NewReader(`
+[rule browser]
+class = Firefox
+workspace = 2
+column = left
+
+[rule dialogs]
+type = Dialog
+title = ^Open
+floating = true
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := parseRules(sections)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 {
+		t.Fatal("Expected 2 rules, got", len(rules))
+	}
+	if r := rules[0]; r.Name != "browser" || r.Workspace != 2 || r.Column != "left" {
+		t.Error("Wrong rule", r)
+	}
+	if !rules[0].Match("Navigator", "Firefox", "", nil) {
+		t.Error("Rule doesn't match the class")
+	}
+	if rules[1].Match("gimp", "Gimp", "Open Image", []string{"normal"}) {
+		t.Error("Rule matches other window type")
+	}
+	if !rules[1].Match("gimp", "Gimp", "Open Image", []string{"dialog"}) || !rules[1].Floating {
+		t.Error("Rule doesn't match the dialog")
+	}
+}
+
+func TestParseRulesInvalid(t *testing.T) {
+	invalid := []string{
+		"[rule all]\nfloating = true\n",
+		"[rule x]\nclass = X\ncolumn = middle\n",
+		"[rule x]\nclass = X\ncolumn = 0\n",
+		"[rule x]\nclass = X\nworkspace = 0\n",
+		"[rule x]\ntitle = (\n",
+		"[rule x]\nclass = X\nsticky = true\n",
+	}
+	for _, content := range invalid {
+		sections, err := parseFile(strings.NewReader(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := parseRules(sections); err == nil {
+			t.Errorf("Invalid rule %q accepted", content)
+		}
+	}
+}
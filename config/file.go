@@ -0,0 +1,101 @@
+// Package config parses command line arguments
+// and provides access to them
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Entry represents "key = value" line of the configuration file
+type Entry struct {
+	Key   string
+	Value string
+	Line  int
+}
+
+// Section represents named group of entries of the configuration file.
+// Entries preceding the first section header belong to the section
+// with empty name, they have the same names as command line arguments
+type Section struct {
+	Name    string
+	Entries []Entry
+}
+
+// DefaultPath returns default location of the configuration file,
+// $XDG_CONFIG_HOME/wmwm/config or ~/.config/wmwm/config
+func DefaultPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		dir = filepath.Join(os.Getenv("HOME"), ".config")
+	}
+	return filepath.Join(dir, "wmwm", "config")
+}
+
+// readFile reads sections of the configuration file.
+// Missing file is an error only if its path was given explicitly
+func (s *settings) readFile(required bool) error {
+	file, err := os.Open(s.path)
+	if os.IsNotExist(err) && !required {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	s.sections, err = parseFile(file)
+	if err != nil {
+		return fmt.Errorf("%s: %v", s.path, err)
+	}
+	return nil
+}
+
+// Section returns entries of the named section
+func (s *settings) Section(name string) []Entry {
+	var entries []Entry
+	for _, section := range s.sections {
+		if section.Name == name {
+			entries = append(entries, section.Entries...)
+		}
+	}
+	return entries
+}
+
+// parseFile reads sections of the configuration file.
+// Empty lines and lines starting with '#' are skipped
+func parseFile(r io.Reader) ([]Section, error) {
+	sections := []Section{{Name: ""}}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: unterminated section header", n)
+			}
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			sections = append(sections, Section{Name: name})
+			continue
+		}
+
+		i := strings.Index(line, "=")
+		if i < 1 {
+			return nil, fmt.Errorf("line %d: expected key = value", n)
+		}
+		last := &sections[len(sections)-1]
+		last.Entries = append(last.Entries, Entry{
+			Key:   strings.TrimSpace(line[:i]),
+			Value: strings.TrimSpace(line[i+1:]),
+			Line:  n,
+		})
+	}
+
+	return sections, scanner.Err()
+}
@@ -2,68 +2,130 @@
 // and provides access to them
 package config
 
+import "sync"
+
 var (
+	mu      sync.RWMutex
+	current = newSettings()
+)
+
+// settings holds values of all configuration options.
+// Once published settings are never modified, reload replaces them
+type settings struct {
 	color         ColorFlag
 	paddingTop    NonNegativeFlag
 	paddingBottom NonNegativeFlag
 	borderWidth   NonNegativeFlag
 	nameLimit     NonNegativeFlag
+	resizeStep    NonNegativeFlag
+	minColumn     NonNegativeFlag
 	commands      StringsFlag
 	terminal      string
 	launcher      string
 	locker        string
 	debug         bool
-)
+	replace       bool
+	path          string
+	sections      []Section
+	bindings      []Binding
+	rules         []Rule
+}
+
+func get() *settings {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+func set(s *settings) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = s
+}
 
 // Color returns --color command line argument value
 func Color() uint32 {
-	return uint32(color)
+	return uint32(get().color)
 }
 
 // PaddingTop returns --padding-top command line argument value
 func PaddingTop() int {
-	return int(paddingTop)
+	return int(get().paddingTop)
 }
 
 // PaddingBottom returns --padding-bottom command line argument value
 func PaddingBottom() int {
-	return int(paddingBottom)
+	return int(get().paddingBottom)
 }
 
 // BorderWidth returns --border-width command line argument value
 func BorderWidth() int {
-	return int(borderWidth)
+	return int(get().borderWidth)
 }
 
 // NameLimit returns --name-limit command line argument value
 func NameLimit() int {
+	nameLimit := get().nameLimit
 	if nameLimit < 1 {
 		return 1
 	}
 	return int(nameLimit)
 }
 
+// ResizeStep returns --resize-step command line argument value
+// as a fraction of the workspace width or of the column height
+func ResizeStep() float64 {
+	step := get().resizeStep
+	if step < 1 {
+		step = 1
+	} else if step > 50 {
+		step = 50
+	}
+	return float64(step) / 100
+}
+
+// MinColumnWidth returns --min-column-width command line argument value
+func MinColumnWidth() int {
+	return int(get().minColumn)
+}
+
 // Commands returns values of --exec command line arguments
 func Commands() []string {
-	return commands.Value
+	return get().commands.Value
 }
 
 // TerminalCmd returns value of --term command line argument
 func TerminalCmd() string {
-	return terminal
+	return get().terminal
 }
 
 // LockerCmd returns value of --lock command line argument
 func LockerCmd() string {
-	return locker
+	return get().locker
 }
 
 // LauncherCmd returns value of --launcher command line argument
 func LauncherCmd() string {
-	return launcher
+	return get().launcher
 }
 
 // Debug returns value of --debug command line argument
 func Debug() bool {
-	return debug
+	return get().debug
+}
+
+// Replace returns value of --replace command line argument
+func Replace() bool {
+	return get().replace
+}
+
+// Path returns path of the configuration file
+func Path() string {
+	return get().path
+}
+
+// Sections returns sections of the configuration file
+// in order of their appearance
+func Sections() []Section {
+	return get().sections
 }
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"os"
 	"strconv"
 )
 
@@ -71,20 +72,105 @@ func (s *StringsFlag) Set(v string) error {
 	return nil
 }
 
-// ParseArgs parses CLI arguments
-func ParseArgs() {
-	flag.Var(&color, "color", "Background and border color")
-	flag.Var(&paddingTop, "padding-top", "Value of top padding")
-	flag.Var(&paddingBottom, "padding-bottom", "Value of bottom padding")
-	flag.Var(&borderWidth, "border-width", "Border width of focused window")
-	flag.Var(&nameLimit, "name-limit", "Maximum length of workspace name")
-	flag.Var(&commands, "exec", "Commands to execute at startup")
-	flag.StringVar(&terminal, "term", "xterm", "A command to launch terminal emulator")
-	flag.StringVar(&launcher, "launcher", "rofi -show run", "A command to show application launcher")
-	flag.StringVar(&locker, "lock", "slock", "A command to lock screen")
-	flag.BoolVar(
-		&debug, "debug", false,
+// newSettings returns settings filled with default values
+func newSettings() *settings {
+	bindings, _ := parseBindings(nil)
+	return &settings{
+		resizeStep: 5,
+		minColumn:  100,
+		terminal:   "xterm",
+		launcher:   "rofi -show run",
+		locker:     "slock",
+		path:       DefaultPath(),
+		bindings:   bindings,
+	}
+}
+
+// flagSet binds command line arguments to the settings
+func (s *settings) flagSet(handling flag.ErrorHandling) *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], handling)
+	fs.Var(&s.color, "color", "Background and border color")
+	fs.Var(&s.paddingTop, "padding-top", "Value of top padding")
+	fs.Var(&s.paddingBottom, "padding-bottom", "Value of bottom padding")
+	fs.Var(&s.borderWidth, "border-width", "Border width of focused window")
+	fs.Var(&s.nameLimit, "name-limit", "Maximum length of workspace name")
+	fs.Var(&s.resizeStep, "resize-step", "Percent of the width or height columns and windows are resized by")
+	fs.Var(&s.minColumn, "min-column-width", "Minimum width of the resized column")
+	fs.Var(&s.commands, "exec", "Commands to execute at startup")
+	fs.StringVar(&s.terminal, "term", s.terminal, "A command to launch terminal emulator")
+	fs.StringVar(&s.launcher, "launcher", s.launcher, "A command to show application launcher")
+	fs.StringVar(&s.locker, "lock", s.locker, "A command to lock screen")
+	fs.StringVar(&s.path, "config", s.path, "Path to the configuration file")
+	fs.BoolVar(
+		&s.debug, "debug", false,
 		"Outputs debug information to Stderr",
 	)
-	flag.Parse()
+	fs.BoolVar(
+		&s.replace, "replace", false,
+		"Replace currently running window manager",
+	)
+	return fs
+}
+
+// cliArgs holds command line arguments given at startup
+var cliArgs []string
+
+// ParseArgs parses CLI arguments and the configuration file.
+// Arguments given in command line take precedence over the file
+func ParseArgs() error {
+	cliArgs = os.Args[1:]
+	return load(cliArgs, flag.ExitOnError)
+}
+
+// Reload re-reads the configuration file. Command line arguments
+// still take precedence. Previous configuration stays in effect
+// if the file can't be read
+func Reload() error {
+	return load(cliArgs, flag.ContinueOnError)
+}
+
+func load(args []string, handling flag.ErrorHandling) error {
+	s := newSettings()
+	fs := s.flagSet(handling)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	if err := s.readFile(explicit["config"]); err != nil {
+		return err
+	}
+
+	for _, entry := range s.Section("") {
+		if explicit[entry.Key] {
+			continue
+		}
+		if entry.Key == "config" || fs.Lookup(entry.Key) == nil {
+			return fmt.Errorf(
+				"%s:%d: unknown option %q", s.path, entry.Line, entry.Key,
+			)
+		}
+		if err := fs.Set(entry.Key, entry.Value); err != nil {
+			return fmt.Errorf("%s:%d: %v", s.path, entry.Line, err)
+		}
+	}
+
+	bindings, err := parseBindings(s.Section("bindings"))
+	if err != nil {
+		return fmt.Errorf("%s: %v", s.path, err)
+	}
+	s.bindings = bindings
+
+	rules, err := parseRules(s.sections)
+	if err != nil {
+		return fmt.Errorf("%s: %v", s.path, err)
+	}
+	s.rules = rules
+
+	set(s)
+	return nil
 }
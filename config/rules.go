@@ -0,0 +1,133 @@
+// Package config parses command line arguments
+// and provides access to them
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Rule describes placement of the new windows matching its criteria.
+// Rules are set in "[rule NAME]" sections of the configuration file
+type Rule struct {
+	Name string
+
+	// Criteria, empty ones match any window
+	Class    string
+	Instance string
+	Title    *regexp.Regexp
+	Type     string
+
+	// Workspace is zero if the window goes to the current workspace
+	Workspace uint32
+	// Column is "left", "right", the column number or empty
+	Column     string
+	Floating   bool
+	Fullscreen bool
+	NoFocus    bool
+}
+
+// Rules returns window rules in order of their appearance
+func Rules() []Rule {
+	return get().rules
+}
+
+// MatchRule returns the first rule matching the window.
+// Types are names of _NET_WM_WINDOW_TYPE atoms without
+// the prefix in lower case, e.g. "dialog"
+func MatchRule(instance, class, title string, types []string) (Rule, bool) {
+	for _, rule := range Rules() {
+		if rule.Match(instance, class, title, types) {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+// Match checks whether the window satisfies criteria of the rule
+func (rule Rule) Match(instance, class, title string, types []string) bool {
+	if rule.Class != "" && rule.Class != class {
+		return false
+	}
+	if rule.Instance != "" && rule.Instance != instance {
+		return false
+	}
+	if rule.Title != nil && !rule.Title.MatchString(title) {
+		return false
+	}
+	if rule.Type == "" {
+		return true
+	}
+	for _, t := range types {
+		if t == rule.Type {
+			return true
+		}
+	}
+	return false
+}
+
+func parseRules(sections []Section) ([]Rule, error) {
+	var rules []Rule
+	for _, section := range sections {
+		fields := strings.Fields(section.Name)
+		if len(fields) < 1 || fields[0] != "rule" {
+			continue
+		}
+
+		rule, err := parseRule(strings.Join(fields[1:], " "), section.Entries)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func parseRule(name string, entries []Entry) (Rule, error) {
+	rule := Rule{Name: name}
+	for _, entry := range entries {
+		var err error
+		switch entry.Key {
+		case "class":
+			rule.Class = entry.Value
+		case "instance":
+			rule.Instance = entry.Value
+		case "title":
+			rule.Title, err = regexp.Compile(entry.Value)
+		case "type":
+			rule.Type = strings.ToLower(entry.Value)
+		case "workspace":
+			var n uint64
+			n, err = strconv.ParseUint(entry.Value, 10, 32)
+			if err == nil && n < 1 {
+				err = errors.New("workspace numbers start from 1")
+			}
+			rule.Workspace = uint32(n)
+		case "column":
+			n, perr := strconv.Atoi(entry.Value)
+			if entry.Value != "left" && entry.Value != "right" && (perr != nil || n < 1) {
+				err = errors.New("column should be left, right or the column number")
+			}
+			rule.Column = entry.Value
+		case "floating":
+			rule.Floating, err = strconv.ParseBool(entry.Value)
+		case "fullscreen":
+			rule.Fullscreen, err = strconv.ParseBool(entry.Value)
+		case "nofocus":
+			rule.NoFocus, err = strconv.ParseBool(entry.Value)
+		default:
+			err = fmt.Errorf("unknown rule option %q", entry.Key)
+		}
+		if err != nil {
+			return rule, fmt.Errorf("line %d: %v", entry.Line, err)
+		}
+	}
+
+	if rule.Class == "" && rule.Instance == "" && rule.Title == nil && rule.Type == "" {
+		return rule, fmt.Errorf("rule %q matches every window", name)
+	}
+	return rule, nil
+}
@@ -0,0 +1,155 @@
+// Package main implements logic of the window manager
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/xproto"
+	"github.com/Zamony/wmwm/ipc"
+	"github.com/Zamony/wmwm/logging"
+)
+
+// replyTimeout limits time the window manager waits
+// for the reply to be written before it stops
+const replyTimeout = time.Second
+
+// ControlRequest represents command received from the control socket.
+// Done is closed when the reply is written to the client
+type ControlRequest struct {
+	Command string
+	Reply   chan ipc.Response
+	Done    chan struct{}
+}
+
+// ListenControl accepts connections on the control socket
+// and passes received commands to the requests channel
+func ListenControl(path string, requests chan<- ControlRequest) (net.Listener, error) {
+	if _, err := os.Stat(path); err == nil {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, errors.New("Control socket is in use: " + path)
+		}
+		os.Remove(path)
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				logging.Println(err)
+				return
+			}
+			go serveControl(conn, requests)
+		}
+	}()
+
+	return listener, nil
+}
+
+func serveControl(conn net.Conn, requests chan<- ControlRequest) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadString('\n')
+	if err != nil && line == "" {
+		return
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) > 0 && fields[0] == "subscribe" {
+		streamEvents(conn, reader, fields[1:])
+		return
+	}
+
+	request := ControlRequest{
+		strings.TrimSpace(line), make(chan ipc.Response, 1), make(chan struct{}),
+	}
+	requests <- request
+	if err := json.NewEncoder(conn).Encode(<-request.Reply); err != nil {
+		logging.Println(err)
+	}
+	close(request.Done)
+}
+
+// streamEvents sends events with the specified names
+// to the client until it closes connection
+func streamEvents(conn net.Conn, reader *bufio.Reader, names []string) {
+	encoder := json.NewEncoder(conn)
+	wanted := make(map[string]bool)
+	for _, name := range names {
+		if !ipc.IsEvent(name) {
+			encoder.Encode(ipc.Response{Error: "Unknown event " + name})
+			return
+		}
+		wanted[name] = true
+	}
+
+	events := eventHub.Subscribe()
+	defer eventHub.Unsubscribe(events)
+	go func() {
+		// Client isn't supposed to send anything else
+		reader.WriteTo(ioutil.Discard)
+		eventHub.Unsubscribe(events)
+	}()
+
+	if err := encoder.Encode(ipc.Response{Ok: true}); err != nil {
+		return
+	}
+	for event := range events {
+		if len(wanted) > 0 && !wanted[event.Event] {
+			continue
+		}
+		if err := encoder.Encode(event); err != nil {
+			return
+		}
+	}
+}
+
+// handleControl performs the requested command and replies to the client.
+// errQuit or errRestart is returned if the window manager should stop,
+// they are returned after the reply is written or the timeout expires
+func handleControl(
+	request ControlRequest, conn *xgb.Conn, xroot xproto.ScreenInfo,
+	bindings *Bindings, manager *WorkspaceManager,
+) error {
+	if request.Command == "get-tree" {
+		data, err := json.Marshal(manager.Tree(conn))
+		if err != nil {
+			request.Reply <- ipc.Response{Error: err.Error()}
+			return nil
+		}
+		request.Reply <- ipc.Response{Ok: true, Data: data}
+		return nil
+	}
+
+	action, err := ParseAction(request.Command)
+	if err == nil {
+		err = runAction(action, conn, xroot, bindings, manager)
+	}
+
+	switch err {
+	case nil:
+		request.Reply <- ipc.Response{Ok: true}
+	case errQuit, errRestart:
+		request.Reply <- ipc.Response{Ok: true}
+		select {
+		case <-request.Done:
+		case <-time.After(replyTimeout):
+		}
+	default:
+		request.Reply <- ipc.Response{Error: err.Error()}
+	}
+	return err
+}
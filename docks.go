@@ -0,0 +1,80 @@
+// Package main implements logic of the window manager
+package main
+
+import (
+	"github.com/BurntSushi/xgb"
+	"github.com/Zamony/wmwm/logging"
+	"github.com/Zamony/wmwm/xutil"
+)
+
+// docks keeps struts of the mapped docks, e.g. panels and status bars.
+// It is accessed only by the event loop
+var docks = make(map[uint32]xutil.Strut)
+
+// manageDock maps the dock and reserves space for it on the monitors
+func manageDock(wid uint32, conn *xgb.Conn, manager *WorkspaceManager) {
+	win := NewWindow(wid, manager.Mailbox(), conn)
+	if err := win.Map(); err != nil {
+		logging.Println(err)
+	}
+	strut, _ := xutil.GetStrut(wid, conn)
+	docks[wid] = strut
+	if err := updateMonitors(conn, manager); err != nil {
+		logging.Error("Updating monitors failed:", err)
+	}
+}
+
+// updateDock re-reads strut of the dock reshaping
+// workspaces if the reserved space changed
+func updateDock(wid uint32, conn *xgb.Conn, manager *WorkspaceManager) {
+	old, ok := docks[wid]
+	if !ok {
+		return
+	}
+	strut, _ := xutil.GetStrut(wid, conn)
+	if strut == old {
+		return
+	}
+	docks[wid] = strut
+	if err := updateMonitors(conn, manager); err != nil {
+		logging.Error("Updating monitors failed:", err)
+	}
+}
+
+// forgetDock releases space reserved by the dock,
+// false is returned if the window isn't a known dock
+func forgetDock(wid uint32, conn *xgb.Conn, manager *WorkspaceManager) bool {
+	if _, ok := docks[wid]; !ok {
+		return false
+	}
+	delete(docks, wid)
+	if err := updateMonitors(conn, manager); err != nil {
+		logging.Error("Updating monitors failed:", err)
+	}
+	return true
+}
+
+// dockStruts returns struts of the mapped docks reserving space
+func dockStruts() []xutil.Strut {
+	struts := make([]xutil.Strut, 0, len(docks))
+	for _, strut := range docks {
+		if !strut.IsEmpty() {
+			struts = append(struts, strut)
+		}
+	}
+	return struts
+}
+
+// publishWorkArea sets _NET_WORKAREA to the work areas
+// of the monitors the workspaces are placed on
+func publishWorkArea(conn *xgb.Conn, manager *WorkspaceManager) {
+	areas := make([][4]int, manager.Workspaces())
+	for i := range areas {
+		screen := manager.Screen(uint32(i + 1))
+		x, y, width, height := screen.WorkArea()
+		areas[i] = [4]int{x, y, width, height}
+	}
+	if err := xutil.SetWorkArea(areas, conn); err != nil {
+		logging.Println(err)
+	}
+}
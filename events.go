@@ -0,0 +1,54 @@
+// Package main implements logic of the window manager
+package main
+
+import (
+	"sync"
+
+	"github.com/Zamony/wmwm/ipc"
+)
+
+// eventHub delivers events to the clients subscribed via control socket
+var eventHub = NewEventHub()
+
+// EventHub broadcasts events to subscribers.
+// Slow subscribers miss events instead of blocking the window manager
+type EventHub struct {
+	mu          sync.Mutex
+	subscribers map[chan ipc.Event]bool
+}
+
+// NewEventHub creates instance of EventHub
+func NewEventHub() *EventHub {
+	return &EventHub{subscribers: make(map[chan ipc.Event]bool)}
+}
+
+// Subscribe returns channel receiving published events
+func (hub *EventHub) Subscribe() chan ipc.Event {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	ch := make(chan ipc.Event, 64)
+	hub.subscribers[ch] = true
+	return ch
+}
+
+// Unsubscribe stops delivering events and closes the channel
+func (hub *EventHub) Unsubscribe(ch chan ipc.Event) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	if hub.subscribers[ch] {
+		delete(hub.subscribers, ch)
+		close(ch)
+	}
+}
+
+// Publish sends event to all subscribers
+func (hub *EventHub) Publish(event ipc.Event) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	for ch := range hub.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Package main implements logic of the window manager
+package main
+
+import (
+	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/xproto"
+	"github.com/Zamony/wmwm/logging"
+	"github.com/Zamony/wmwm/proto"
+	"github.com/Zamony/wmwm/xutil"
+)
+
+// handleClientMessage performs requests sent by pagers and other tools
+func handleClientMessage(e xproto.ClientMessageEvent, conn *xgb.Conn, manager *WorkspaceManager) {
+	if e.Format != 32 {
+		return
+	}
+	wid, data := uint32(e.Window), e.Data.Data32
+
+	switch e.Type {
+	case xutil.GetAtom("_NET_CURRENT_DESKTOP", conn):
+		switchWorkspace(data[0]+1, conn, manager)
+	case xutil.GetAtom("_NET_WM_DESKTOP", conn):
+		moveToDesktop(wid, data[0]+1, conn, manager)
+	case xutil.GetAtom("_NET_ACTIVE_WINDOW", conn):
+		activateWindow(wid, conn, manager)
+	case xutil.GetAtom("_NET_CLOSE_WINDOW", conn):
+		if owner, ok := clients.Owner(wid); ok {
+			win := NewWindow(0, manager.Mailbox(), conn)
+			win.SendCloseWindow(owner, wid)
+		}
+	case xutil.GetAtom("_NET_WM_STATE", conn):
+		fullscreen := uint32(xutil.GetAtom("_NET_WM_STATE_FULLSCREEN", conn))
+		owner, ok := clients.Owner(wid)
+		if ok && (data[1] == fullscreen || data[2] == fullscreen) {
+			win := NewWindow(wid, manager.Mailbox(), conn)
+			win.SendFullscreenState(owner, int(data[0]))
+		}
+	case xutil.GetAtom("_NET_MOVERESIZE_WINDOW", conn):
+		configureWindow(moveResizeRequest(wid, data), conn, manager)
+	default:
+		logging.Println("Unsupported client message", e.Type)
+	}
+}
+
+// activateWindow focuses managed window switching to its workspace
+func activateWindow(wid uint32, conn *xgb.Conn, manager *WorkspaceManager) {
+	owner, ok := clients.Owner(wid)
+	if !ok {
+		return
+	}
+	switchWorkspace(owner, conn, manager)
+	win := NewWindow(wid, manager.Mailbox(), conn)
+	win.SendFocusHere()
+}
+
+// configureWindow passes request to the workspace managing the window,
+// the request is applied at once if the window isn't managed
+func configureWindow(e xproto.ConfigureRequestEvent, conn *xgb.Conn, manager *WorkspaceManager) {
+	if id, ok := clients.Owner(uint32(e.Window)); ok {
+		win := NewWindow(uint32(e.Window), manager.Mailbox(), conn)
+		win.SendConfigure(id, e)
+	} else if err := xutil.ConfigureFromRequest(e, e.ValueMask, conn); err != nil {
+		logging.Println(err)
+	}
+}
+
+// moveResizeRequest converts _NET_MOVERESIZE_WINDOW
+// client message to the configure request
+func moveResizeRequest(wid uint32, data []uint32) xproto.ConfigureRequestEvent {
+	e := xproto.ConfigureRequestEvent{
+		Window: xproto.Window(wid),
+		X:      int16(data[1]),
+		Y:      int16(data[2]),
+		Width:  uint16(data[3]),
+		Height: uint16(data[4]),
+	}
+	// Bits 8-11 of the first value tell which of x, y, width, height are set
+	masks := []uint16{
+		xproto.ConfigWindowX, xproto.ConfigWindowY,
+		xproto.ConfigWindowWidth, xproto.ConfigWindowHeight,
+	}
+	for i, mask := range masks {
+		if data[0]&(1<<uint(8+i)) != 0 {
+			e.ValueMask |= mask
+		}
+	}
+	return e
+}
+
+// moveToDesktop moves managed window to the workspace with the specified id
+func moveToDesktop(wid, id uint32, conn *xgb.Conn, manager *WorkspaceManager) {
+	owner, ok := clients.Owner(wid)
+	if !ok || owner == id || id < 1 || id > manager.Workspaces() {
+		return
+	}
+
+	win := NewWindow(owner, manager.Mailbox(), conn)
+	win.SendReattachWith(id, proto.DetachOptions{Window: wid, Show: manager.Visible(id)})
+}
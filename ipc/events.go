@@ -0,0 +1,92 @@
+// Package ipc defines protocol of the window manager control socket.
+// Client sends a single line containing a command, e.g. "focus left",
+// and receives a single line containing JSON encoded Response
+package ipc
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+)
+
+// Names of the events sent to subscribers
+const (
+	EventWorkspace = "workspace"
+	EventAttach    = "attach"
+	EventRemove    = "remove"
+	EventFocus     = "focus"
+	EventLayout    = "layout"
+	EventTitle     = "title"
+)
+
+// Event represents change of the window manager state.
+// After "subscribe" command followed by names of the events
+// client receives Response and then one Event per line
+type Event struct {
+	Event     string `json:"event"`
+	Workspace uint32 `json:"workspace"`
+	Window    uint32 `json:"window,omitempty"`
+	Title     string `json:"title,omitempty"`
+	Layout    string `json:"layout,omitempty"`
+	Sizing    string `json:"sizing,omitempty"`
+}
+
+// IsEvent checks whether there is an event with the specified name
+func IsEvent(name string) bool {
+	switch name {
+	case EventWorkspace, EventAttach, EventRemove,
+		EventFocus, EventLayout, EventTitle:
+		return true
+	}
+	return false
+}
+
+// Subscription represents stream of events
+type Subscription struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// Subscribe starts receiving events with the specified names,
+// all events are received if no names are given
+func Subscribe(names ...string) (*Subscription, error) {
+	command := strings.Join(append([]string{"subscribe"}, names...), " ")
+	conn, err := Dial(command)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := &Subscription{conn, bufio.NewReader(conn)}
+	var response Response
+	if err := sub.next(&response); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !response.Ok {
+		conn.Close()
+		return nil, errors.New(response.Error)
+	}
+	return sub, nil
+}
+
+// Next waits for the next event
+func (sub *Subscription) Next() (Event, error) {
+	var event Event
+	err := sub.next(&event)
+	return event, err
+}
+
+// Close stops receiving events
+func (sub *Subscription) Close() error {
+	return sub.conn.Close()
+}
+
+func (sub *Subscription) next(v interface{}) error {
+	line, err := sub.reader.ReadBytes('\n')
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(line, v)
+}
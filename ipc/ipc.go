@@ -0,0 +1,73 @@
+// Package ipc defines protocol of the window manager control socket.
+// Client sends a single line containing a command, e.g. "focus left",
+// and receives a single line containing JSON encoded Response
+package ipc
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Response represents reply of the window manager to a command
+type Response struct {
+	Ok    bool            `json:"ok"`
+	Error string          `json:"error,omitempty"`
+	Data  json.RawMessage `json:"data,omitempty"`
+}
+
+// SocketPath returns path of the control socket. It can be set
+// with $WMWM_SOCKET, otherwise it depends on the X display
+func SocketPath() string {
+	if path := os.Getenv("WMWM_SOCKET"); path != "" {
+		return path
+	}
+
+	display := strings.NewReplacer(":", "", "/", "_").Replace(os.Getenv("DISPLAY"))
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = os.TempDir()
+		display = fmt.Sprintf("%d-%s", os.Getuid(), display)
+	}
+	return filepath.Join(dir, "wmwm-"+display+".sock")
+}
+
+// Dial connects to the control socket and sends the command
+func Dial(command string) (net.Conn, error) {
+	conn, err := net.Dial("unix", SocketPath())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fmt.Fprintln(conn, command); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// Send sends the command to the window manager and waits for the response
+func Send(command string) (Response, error) {
+	var response Response
+	conn, err := Dial(command)
+	if err != nil {
+		return response, err
+	}
+	defer conn.Close()
+
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		return response, err
+	}
+	if err := json.Unmarshal(line, &response); err != nil {
+		return response, err
+	}
+	if !response.Ok {
+		return response, errors.New(response.Error)
+	}
+	return response, nil
+}
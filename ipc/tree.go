@@ -0,0 +1,53 @@
+// Package ipc defines protocol of the window manager control socket.
+// Client sends a single line containing a command, e.g. "focus left",
+// and receives a single line containing JSON encoded Response
+package ipc
+
+// Tree describes state of all workspaces,
+// it is returned by "get-tree" command
+type Tree struct {
+	Current uint32 `json:"current"`
+	// Visible lists workspaces shown on the monitors in their order
+	Visible    []uint32         `json:"visible"`
+	Workspaces []WorkspaceState `json:"workspaces"`
+}
+
+// PositionFloating is the position of the column holding floating windows
+const PositionFloating = "floating"
+
+// WorkspaceState describes workspace and its columns.
+// Sizing describes width of the columns of the "columns" layout
+type WorkspaceState struct {
+	ID      uint32        `json:"id"`
+	Layout  string        `json:"layout"`
+	Sizing  string        `json:"sizing,omitempty"`
+	Focus   uint32        `json:"focus"`
+	Columns []ColumnState `json:"columns"`
+}
+
+// ColumnState describes column of the workspace. Position is
+// the number of tiled column counting from 1 or "floating".
+// Ratio is a share of the workspace width taken by the column
+type ColumnState struct {
+	Position string        `json:"position"`
+	X        int           `json:"x"`
+	Width    int           `json:"width"`
+	Ratio    float64       `json:"ratio"`
+	Windows  []WindowState `json:"windows"`
+}
+
+// WindowState describes managed window. Weight is a share
+// of the column height taken by the tiled window
+type WindowState struct {
+	ID      uint32  `json:"id"`
+	Title   string  `json:"title"`
+	X       int     `json:"x"`
+	Y       int     `json:"y"`
+	Width   int     `json:"width"`
+	Height  int     `json:"height"`
+	Focused bool    `json:"focused"`
+	Weight  float64 `json:"weight"`
+
+	Floating   bool `json:"floating,omitempty"`
+	Fullscreen bool `json:"fullscreen,omitempty"`
+}
@@ -0,0 +1,349 @@
+// Package kbrd provides constants representing physical keys
+// and the mapping from physical keys to logical keys
+package kbrd
+
+import (
+	"strings"
+
+	"github.com/BurntSushi/xgb/xproto"
+)
+
+// keysyms maps names of the keys to their KeySyms
+var keysyms = map[string]xproto.Keysym{
+	"BackSpace":      XK_BackSpace,
+	"Tab":            XK_Tab,
+	"Linefeed":       XK_Linefeed,
+	"Clear":          XK_Clear,
+	"Return":         XK_Return,
+	"Pause":          XK_Pause,
+	"Scroll_Lock":    XK_Scroll_Lock,
+	"Sys_Req":        XK_Sys_Req,
+	"Escape":         XK_Escape,
+	"Delete":         XK_Delete,
+	"space":          XK_space,
+	"exclam":         XK_exclam,
+	"quotedbl":       XK_quotedbl,
+	"numbersign":     XK_numbersign,
+	"dollar":         XK_dollar,
+	"percent":        XK_percent,
+	"ampersand":      XK_ampersand,
+	"apostrophe":     XK_apostrophe,
+	"quoteright":     XK_quoteright,
+	"parenleft":      XK_parenleft,
+	"parenright":     XK_parenright,
+	"asterisk":       XK_asterisk,
+	"plus":           XK_plus,
+	"comma":          XK_comma,
+	"minus":          XK_minus,
+	"period":         XK_period,
+	"slash":          XK_slash,
+	"0":              XK_0,
+	"1":              XK_1,
+	"2":              XK_2,
+	"3":              XK_3,
+	"4":              XK_4,
+	"5":              XK_5,
+	"6":              XK_6,
+	"7":              XK_7,
+	"8":              XK_8,
+	"9":              XK_9,
+	"colon":          XK_colon,
+	"semicolon":      XK_semicolon,
+	"less":           XK_less,
+	"equal":          XK_equal,
+	"greater":        XK_greater,
+	"question":       XK_question,
+	"at":             XK_at,
+	"A":              XK_A,
+	"B":              XK_B,
+	"C":              XK_C,
+	"D":              XK_D,
+	"E":              XK_E,
+	"F":              XK_F,
+	"G":              XK_G,
+	"H":              XK_H,
+	"I":              XK_I,
+	"J":              XK_J,
+	"K":              XK_K,
+	"L":              XK_L,
+	"M":              XK_M,
+	"N":              XK_N,
+	"O":              XK_O,
+	"P":              XK_P,
+	"Q":              XK_Q,
+	"R":              XK_R,
+	"S":              XK_S,
+	"T":              XK_T,
+	"U":              XK_U,
+	"V":              XK_V,
+	"W":              XK_W,
+	"X":              XK_X,
+	"Y":              XK_Y,
+	"Z":              XK_Z,
+	"bracketleft":    XK_bracketleft,
+	"backslash":      XK_backslash,
+	"bracketright":   XK_bracketright,
+	"asciicircum":    XK_asciicircum,
+	"underscore":     XK_underscore,
+	"grave":          XK_grave,
+	"quoteleft":      XK_quoteleft,
+	"a":              XK_a,
+	"b":              XK_b,
+	"c":              XK_c,
+	"d":              XK_d,
+	"e":              XK_e,
+	"f":              XK_f,
+	"g":              XK_g,
+	"h":              XK_h,
+	"i":              XK_i,
+	"j":              XK_j,
+	"k":              XK_k,
+	"l":              XK_l,
+	"m":              XK_m,
+	"n":              XK_n,
+	"o":              XK_o,
+	"p":              XK_p,
+	"q":              XK_q,
+	"r":              XK_r,
+	"s":              XK_s,
+	"t":              XK_t,
+	"u":              XK_u,
+	"v":              XK_v,
+	"w":              XK_w,
+	"x":              XK_x,
+	"y":              XK_y,
+	"z":              XK_z,
+	"braceleft":      XK_braceleft,
+	"bar":            XK_bar,
+	"braceright":     XK_braceright,
+	"asciitilde":     XK_asciitilde,
+	"nobreakspace":   XK_nobreakspace,
+	"exclamdown":     XK_exclamdown,
+	"cent":           XK_cent,
+	"sterling":       XK_sterling,
+	"currency":       XK_currency,
+	"yen":            XK_yen,
+	"brokenbar":      XK_brokenbar,
+	"section":        XK_section,
+	"diaeresis":      XK_diaeresis,
+	"copyright":      XK_copyright,
+	"ordfeminine":    XK_ordfeminine,
+	"guillemotleft":  XK_guillemotleft,
+	"notsign":        XK_notsign,
+	"hyphen":         XK_hyphen,
+	"registered":     XK_registered,
+	"macron":         XK_macron,
+	"degree":         XK_degree,
+	"plusminus":      XK_plusminus,
+	"twosuperior":    XK_twosuperior,
+	"threesuperior":  XK_threesuperior,
+	"acute":          XK_acute,
+	"mu":             XK_mu,
+	"paragraph":      XK_paragraph,
+	"periodcentered": XK_periodcentered,
+	"cedilla":        XK_cedilla,
+	"onesuperior":    XK_onesuperior,
+	"masculine":      XK_masculine,
+	"guillemotright": XK_guillemotright,
+	"onequarter":     XK_onequarter,
+	"onehalf":        XK_onehalf,
+	"threequarters":  XK_threequarters,
+	"questiondown":   XK_questiondown,
+	"Agrave":         XK_Agrave,
+	"Aacute":         XK_Aacute,
+	"Acircumflex":    XK_Acircumflex,
+	"Atilde":         XK_Atilde,
+	"Adiaeresis":     XK_Adiaeresis,
+	"Aring":          XK_Aring,
+	"AE":             XK_AE,
+	"Ccedilla":       XK_Ccedilla,
+	"Egrave":         XK_Egrave,
+	"Eacute":         XK_Eacute,
+	"Ecircumflex":    XK_Ecircumflex,
+	"Ediaeresis":     XK_Ediaeresis,
+	"Igrave":         XK_Igrave,
+	"Iacute":         XK_Iacute,
+	"Icircumflex":    XK_Icircumflex,
+	"Idiaeresis":     XK_Idiaeresis,
+	"ETH":            XK_ETH,
+	"Eth":            XK_Eth,
+	"Ntilde":         XK_Ntilde,
+	"Ograve":         XK_Ograve,
+	"Oacute":         XK_Oacute,
+	"Ocircumflex":    XK_Ocircumflex,
+	"Otilde":         XK_Otilde,
+	"Odiaeresis":     XK_Odiaeresis,
+	"multiply":       XK_multiply,
+	"Oslash":         XK_Oslash,
+	"Ooblique":       XK_Ooblique,
+	"Ugrave":         XK_Ugrave,
+	"Uacute":         XK_Uacute,
+	"Ucircumflex":    XK_Ucircumflex,
+	"Udiaeresis":     XK_Udiaeresis,
+	"Yacute":         XK_Yacute,
+	"THORN":          XK_THORN,
+	"Thorn":          XK_Thorn,
+	"ssharp":         XK_ssharp,
+	"agrave":         XK_agrave,
+	"aacute":         XK_aacute,
+	"acircumflex":    XK_acircumflex,
+	"atilde":         XK_atilde,
+	"adiaeresis":     XK_adiaeresis,
+	"aring":          XK_aring,
+	"ae":             XK_ae,
+	"ccedilla":       XK_ccedilla,
+	"egrave":         XK_egrave,
+	"eacute":         XK_eacute,
+	"ecircumflex":    XK_ecircumflex,
+	"ediaeresis":     XK_ediaeresis,
+	"igrave":         XK_igrave,
+	"iacute":         XK_iacute,
+	"icircumflex":    XK_icircumflex,
+	"idiaeresis":     XK_idiaeresis,
+	"eth":            XK_eth,
+	"ntilde":         XK_ntilde,
+	"ograve":         XK_ograve,
+	"oacute":         XK_oacute,
+	"ocircumflex":    XK_ocircumflex,
+	"otilde":         XK_otilde,
+	"odiaeresis":     XK_odiaeresis,
+	"division":       XK_division,
+	"oslash":         XK_oslash,
+	"ooblique":       XK_ooblique,
+	"ugrave":         XK_ugrave,
+	"uacute":         XK_uacute,
+	"ucircumflex":    XK_ucircumflex,
+	"udiaeresis":     XK_udiaeresis,
+	"yacute":         XK_yacute,
+	"thorn":          XK_thorn,
+	"ydiaeresis":     XK_ydiaeresis,
+	"Home":           XK_Home,
+	"Left":           XK_Left,
+	"Up":             XK_Up,
+	"Right":          XK_Right,
+	"Down":           XK_Down,
+	"Prior":          XK_Prior,
+	"Page_Up":        XK_Page_Up,
+	"Next":           XK_Next,
+	"Page_Down":      XK_Page_Down,
+	"End":            XK_End,
+	"Begin":          XK_Begin,
+	"F1":             XK_F1,
+	"F2":             XK_F2,
+	"F3":             XK_F3,
+	"F4":             XK_F4,
+	"F5":             XK_F5,
+	"F6":             XK_F6,
+	"F7":             XK_F7,
+	"F8":             XK_F8,
+	"F9":             XK_F9,
+	"F10":            XK_F10,
+	"F11":            XK_F11,
+	"L1":             XK_L1,
+	"F12":            XK_F12,
+	"L2":             XK_L2,
+	"F13":            XK_F13,
+	"L3":             XK_L3,
+	"F14":            XK_F14,
+	"L4":             XK_L4,
+	"F15":            XK_F15,
+	"L5":             XK_L5,
+	"F16":            XK_F16,
+	"L6":             XK_L6,
+	"F17":            XK_F17,
+	"L7":             XK_L7,
+	"F18":            XK_F18,
+	"L8":             XK_L8,
+	"F19":            XK_F19,
+	"L9":             XK_L9,
+	"F20":            XK_F20,
+	"L10":            XK_L10,
+	"F21":            XK_F21,
+	"R1":             XK_R1,
+	"F22":            XK_F22,
+	"R2":             XK_R2,
+	"F23":            XK_F23,
+	"R3":             XK_R3,
+	"F24":            XK_F24,
+	"R4":             XK_R4,
+	"F25":            XK_F25,
+	"R5":             XK_R5,
+	"F26":            XK_F26,
+	"R6":             XK_R6,
+	"F27":            XK_F27,
+	"R7":             XK_R7,
+	"F28":            XK_F28,
+	"R8":             XK_R8,
+	"F29":            XK_F29,
+	"R9":             XK_R9,
+	"F30":            XK_F30,
+	"R10":            XK_R10,
+	"F31":            XK_F31,
+	"R11":            XK_R11,
+	"F32":            XK_F32,
+	"R12":            XK_R12,
+	"F33":            XK_F33,
+	"R13":            XK_R13,
+	"F34":            XK_F34,
+	"R14":            XK_R14,
+	"F35":            XK_F35,
+	"R15":            XK_R15,
+	"Shift_L":        XK_Shift_L,
+	"Shift_R":        XK_Shift_R,
+	"Control_L":      XK_Control_L,
+	"Control_R":      XK_Control_R,
+	"Caps_Lock":      XK_Caps_Lock,
+	"Shift_Lock":     XK_Shift_Lock,
+	"Meta_L":         XK_Meta_L,
+	"Meta_R":         XK_Meta_R,
+	"Alt_L":          XK_Alt_L,
+	"Alt_R":          XK_Alt_R,
+	"Super_L":        XK_Super_L,
+	"Super_R":        XK_Super_R,
+	"Hyper_L":        XK_Hyper_L,
+	"Hyper_R":        XK_Hyper_R,
+}
+
+// modifiers maps names of the modifier keys to their masks
+var modifiers = map[string]uint16{
+	"shift":   xproto.ModMaskShift,
+	"lock":    xproto.ModMaskLock,
+	"control": xproto.ModMaskControl,
+	"ctrl":    xproto.ModMaskControl,
+	"mod1":    xproto.ModMask1,
+	"alt":     xproto.ModMask1,
+	"mod2":    xproto.ModMask2,
+	"mod3":    xproto.ModMask3,
+	"mod4":    xproto.ModMask4,
+	"win":     xproto.ModMask4,
+	"super":   xproto.ModMask4,
+	"mod5":    xproto.ModMask5,
+}
+
+// Keysym returns KeySym of the key by its name as in keysymdef.h,
+// e.g. "Return" or "F1"
+func Keysym(name string) (xproto.Keysym, bool) {
+	sym, ok := keysyms[name]
+	return sym, ok
+}
+
+// Modifier returns mask of the modifier key by its name,
+// e.g. "Shift", "Control" or "Mod4"
+func Modifier(name string) (uint16, bool) {
+	mask, ok := modifiers[strings.ToLower(name)]
+	return mask, ok
+}
+
+// Keycodes returns all keycodes producing the specified KeySym
+func Keycodes(keymap [256][]xproto.Keysym, sym xproto.Keysym) []xproto.Keycode {
+	var codes []xproto.Keycode
+	for i, syms := range keymap {
+		for _, s := range syms {
+			if s == sym {
+				codes = append(codes, xproto.Keycode(i))
+				break
+			}
+		}
+	}
+	return codes
+}
@@ -2,87 +2,141 @@
 package main
 
 import (
-	"errors"
+	"os"
 	"os/exec"
+	"os/signal"
 	"regexp"
+	"syscall"
 
 	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/randr"
 	"github.com/BurntSushi/xgb/xinerama"
 	"github.com/BurntSushi/xgb/xproto"
-	"github.com/Zamony/wmwm/logging"
 	"github.com/Zamony/wmwm/config"
-	"github.com/Zamony/wmwm/kbrd"
+	"github.com/Zamony/wmwm/ipc"
+	"github.com/Zamony/wmwm/logging"
+	"github.com/Zamony/wmwm/proto"
 	"github.com/Zamony/wmwm/xutil"
 )
 
-func processEvents(
-	conn *xgb.Conn, keymap [256][]xproto.Keysym,
-	monitors xutil.MonitorsInfo, manager *WorkspaceManager,
-) {
-eventloop:
+// pumpEvents forwards X events to the channel
+// until connection to the X server is closed
+func pumpEvents(conn *xgb.Conn, events chan<- xgb.Event) {
 	for {
 		event, err := conn.WaitForEvent()
 		if err != nil {
 			logging.Println(err)
 			continue
 		}
+		if event == nil {
+			close(events)
+			return
+		}
+		events <- event
+	}
+}
+
+// processEvents handles X events and control requests. It returns
+// errQuit or errRestart when requested, nil when X connection is closed
+func processEvents(
+	conn *xgb.Conn, xroot xproto.ScreenInfo, bindings *Bindings,
+	manager *WorkspaceManager, requests <-chan ControlRequest,
+) error {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	events := make(chan xgb.Event)
+	go pumpEvents(conn, events)
+
+	for {
+		var event xgb.Event
+		select {
+		case <-hangup:
+			if err := reloadConfig(conn, xroot, bindings, manager); err != nil {
+				logging.Error("Configuration reload failed:", err)
+			}
+			continue
+		case request := <-requests:
+			err := handleControl(request, conn, xroot, bindings, manager)
+			if err == errQuit || err == errRestart {
+				return err
+			}
+			continue
+		case ev, ok := <-events:
+			if !ok {
+				return nil
+			}
+			event = ev
+		}
 
+		monitors := manager.Monitors()
 		switch e := event.(type) {
 		case xproto.KeyPressEvent:
-			err := handleKeyPress(
-				conn, event.(xproto.KeyPressEvent), keymap, manager,
-			)
-			if err != nil {
-				break eventloop
+			action, ok := bindings.Action(e)
+			if !ok {
+				break
+			}
+			err := runAction(action, conn, xroot, bindings, manager)
+			if err == errQuit || err == errRestart {
+				return err
+			} else if err != nil {
+				logging.Error(err)
 			}
 		case xproto.ConfigureRequestEvent:
 			logging.Println(event)
-			ev := xproto.ConfigureNotifyEvent{
-				Event:            e.Window,
-				Window:           e.Window,
-				AboveSibling:     0,
-				X:                e.X,
-				Y:                e.Y,
-				Width:            e.Width,
-				Height:           e.Height,
-				BorderWidth:      0,
-				OverrideRedirect: false,
-			}
-			xproto.SendEventChecked(
-				conn, false, e.Window, xproto.EventMaskStructureNotify,
-				string(ev.Bytes()),
-			)
+			configureWindow(e, conn, manager)
 		case xproto.MapRequestEvent:
 			logging.Println(event)
 			wattr, err := xproto.GetWindowAttributes(conn, e.Window).Reply()
-			if err != nil || !wattr.OverrideRedirect {
-				win := NewWindow(uint32(e.Window), manager.Mailbox(), conn)
-				win.SendAttach(manager.Curr())
-				win.SendActivate(manager.Curr())
+			switch {
+			case err == nil && wattr.OverrideRedirect:
+			case xutil.IsDock(uint32(e.Window), conn):
+				manageDock(uint32(e.Window), conn, manager)
+			default:
+				attachWindow(uint32(e.Window), conn, manager)
 			}
 		case xproto.UnmapNotifyEvent:
 			logging.Println(event)
-			win := NewWindow(uint32(e.Window), manager.Mailbox(), conn)
-			win.SendRemove()
+			if !forgetDock(uint32(e.Window), conn, manager) {
+				win := NewWindow(uint32(e.Window), manager.Mailbox(), conn)
+				win.SendRemove()
+			}
 		case xproto.DestroyNotifyEvent:
 			logging.Println(event)
+			if !forgetDock(uint32(e.Window), conn, manager) {
+				win := NewWindow(uint32(e.Window), manager.Mailbox(), conn)
+				win.SendRemove()
+			}
+		case xproto.PropertyNotifyEvent:
 			win := NewWindow(uint32(e.Window), manager.Mailbox(), conn)
-			win.SendRemove()
+			if xutil.IsNameAtom(e.Atom, conn) {
+				win.SendTitle()
+			} else if e.Atom == xproto.AtomWmNormalHints {
+				win.SendHints()
+			} else if xutil.IsStrutAtom(e.Atom, conn) {
+				updateDock(uint32(e.Window), conn, manager)
+			}
+		case randr.ScreenChangeNotifyEvent:
+			logging.Println(event)
+			if err := updateMonitors(conn, manager); err != nil {
+				logging.Error("Updating monitors failed:", err)
+			}
+		case xproto.ClientMessageEvent:
+			logging.Println(event)
+			handleClientMessage(e, conn, manager)
+		case xproto.SelectionClearEvent:
+			logging.Println(event)
+			if xutil.IsWMSelection(e.Selection, conn) {
+				logging.Println("Replaced by another window manager")
+				return errQuit
+			}
 		case xproto.ButtonPressEvent:
 			logging.Println(event)
 			if e.Child > 0 {
 				win := NewWindow(uint32(e.Child), manager.Mailbox(), conn)
-				if monitors.IsDualSetup() {
-					secondMonitorClick := !monitors.InPrimaryRegion(int(e.RootX))
-					secondMonitorActive := manager.Curr() == manager.SpecialWorkspace()
-					switch {
-					case !secondMonitorClick && secondMonitorActive:
-						win.SendActivate(manager.Prev())
-						manager.SetCurr(manager.Prev())
-					case secondMonitorClick && !secondMonitorActive:
-						win.SendActivate(manager.SpecialWorkspace())
-						manager.SetCurr(manager.SpecialWorkspace())
-					}
+				m := monitors.At(int(e.RootX), int(e.RootY))
+				if m != manager.Monitor() && m < len(manager.VisibleAll()) {
+					win.SendActivate(manager.VisibleOn(m))
+					manager.SetCurr(manager.VisibleOn(m))
 				}
 
 				win.SendFocusHere()
@@ -95,149 +149,130 @@ eventloop:
 	}
 }
 
-func handleKeyPress(conn *xgb.Conn, key xproto.KeyPressEvent, keymap [256][]xproto.Keysym, manager *WorkspaceManager) error {
-	keysym := keymap[key.Detail][0]
-	fkeys := map[xproto.Keysym]uint32{
-		kbrd.XK_F1: 1, kbrd.XK_F2: 2, kbrd.XK_F3: 3, kbrd.XK_F4: 4,
-		kbrd.XK_F5: 5, kbrd.XK_F6: 6, kbrd.XK_F7: 7, kbrd.XK_F8: 8, kbrd.XK_F9: 9,
-	}
-	switch keysym {
-	case kbrd.XK_BackSpace:
-		ctrlActive := (key.State & xproto.ModMaskControl) != 0
-		altActive := (key.State & xproto.ModMask1) != 0
-		if ctrlActive && altActive {
-			return errors.New("Error: quit event")
-		}
-	case kbrd.XK_t:
-		winActive := (key.State & xproto.ModMask4) != 0
-		if winActive {
-			if _, err := RunCommand(config.TerminalCmd()); err != nil {
-				logging.Error("Terminal launch failed")
-			}
-		}
-	case kbrd.XK_f:
-		winActive := (key.State & xproto.ModMask4) != 0
-		if winActive {
-			win := NewWindow(uint32(key.Child), manager.Mailbox(), conn)
-			win.SendMaximize(manager.Curr())
-		}
-	case kbrd.XK_F1, kbrd.XK_F2, kbrd.XK_F3, kbrd.XK_F4, kbrd.XK_F5:
-		fallthrough
-	case kbrd.XK_F6, kbrd.XK_F7, kbrd.XK_F8, kbrd.XK_F9:
-		winActive := (key.State & xproto.ModMask4) != 0
-		if winActive && manager.Curr() != fkeys[keysym] {
-			win := NewWindow(manager.Curr(), manager.Mailbox(), conn)
-			win.SendReattach(fkeys[keysym])
-			isSpecial := manager.Curr() == manager.SpecialWorkspace()
-			if isSpecial && manager.Prev() == fkeys[keysym] {
-				go func() {
-					attachLock.Lock()
-					win.SendActivate(manager.Prev())
-					win.SendActivate(manager.Curr())
-				}()
-			}
-		} else if !winActive && manager.Curr() != fkeys[keysym] {
-			win := NewWindow(uint32(key.Root), manager.Mailbox(), conn)
-			if manager.Curr() == manager.SpecialWorkspace() {
-				win.SendDeactivate(manager.Prev())
-			} else if fkeys[keysym] != manager.SpecialWorkspace() {
-				win.SendDeactivate(manager.Curr())
-			}
-			win.SendActivate(fkeys[keysym])
-			manager.SetCurr(fkeys[keysym])
-		}
-	case kbrd.XK_Left:
-		winActive := (key.State & xproto.ModMask4) != 0
-		ctrlActive := (key.State & xproto.ModMaskControl) != 0
-		altActive := (key.State & xproto.ModMask1) != 0
-		if winActive && ctrlActive && !altActive {
-			win := NewWindow(uint32(key.Child), manager.Mailbox(), conn)
-			win.SendResizeLeft(manager.Curr())
-		}
+// runAction performs the action reloading configuration if requested.
+// errQuit or errRestart is returned if the window manager should stop
+func runAction(
+	action Action, conn *xgb.Conn, xroot xproto.ScreenInfo,
+	bindings *Bindings, manager *WorkspaceManager,
+) error {
+	logging.Println("Action:", action)
+	err := action.Run(conn, manager)
+	if err == errReload {
+		return reloadConfig(conn, xroot, bindings, manager)
+	}
+	return err
+}
 
-		if winActive && !ctrlActive && !altActive {
-			win := NewWindow(uint32(key.Root), manager.Mailbox(), conn)
-			win.SendFocusLeft(manager.Curr())
-		}
+// reloadConfig re-reads configuration file and applies it
+// to the root window and to every workspace keeping windows in place
+func reloadConfig(
+	conn *xgb.Conn, xroot xproto.ScreenInfo,
+	bindings *Bindings, manager *WorkspaceManager,
+) error {
+	if err := config.Reload(); err != nil {
+		return err
+	}
+	logging.Debug = config.Debug()
 
-		if winActive && !ctrlActive && altActive {
-			win := NewWindow(uint32(key.Child), manager.Mailbox(), conn)
-			win.SendMoveLeft()
-		}
+	if err := bindings.Load(conn, xroot); err != nil {
+		return err
+	}
 
-	case kbrd.XK_Right:
-		winActive := (key.State & xproto.ModMask4) != 0
-		ctrlActive := (key.State & xproto.ModMaskControl) != 0
-		altActive := (key.State & xproto.ModMask1) != 0
-		if winActive && ctrlActive && !altActive {
-			win := NewWindow(uint32(key.Child), manager.Mailbox(), conn)
-			win.SendResizeRight(manager.Curr())
-		}
+	if err := xutil.SetRootBackground(config.Color(), conn); err != nil {
+		logging.Error(err)
+	}
 
-		if winActive && !ctrlActive && !altActive {
-			win := NewWindow(uint32(key.Root), manager.Mailbox(), conn)
-			win.SendFocusRight(manager.Curr())
-		}
+	return updateMonitors(conn, manager)
+}
 
-		if winActive && !ctrlActive && altActive {
-			win := NewWindow(uint32(key.Child), manager.Mailbox(), conn)
-			win.SendMoveRight()
-		}
-	case kbrd.XK_Up:
-		winActive := (key.State & xproto.ModMask4) != 0
-		altActive := (key.State & xproto.ModMask1) != 0
-		if winActive && !altActive {
-			win := NewWindow(uint32(key.Root), manager.Mailbox(), conn)
-			win.SendFocusUp(manager.Curr())
-		}
+// updateMonitors re-reads information about connected monitors,
+// hides workspaces of the removed monitors, shows workspaces on
+// the added ones and reshapes every workspace to the new geometry
+func updateMonitors(conn *xgb.Conn, manager *WorkspaceManager) error {
+	monitors, err := xutil.ReadMonitorsInfo(conn)
+	if err != nil {
+		return err
+	}
+	curr := manager.Curr()
+	hidden, shown := manager.SetMonitors(monitors.WithStruts(dockStruts()))
+	publishWorkArea(conn, manager)
 
-		if winActive && altActive {
-			win := NewWindow(uint32(key.Child), manager.Mailbox(), conn)
-			win.SendMoveUp()
-		}
+	win := NewWindow(0, manager.Mailbox(), conn)
+	for _, id := range hidden {
+		win.SendDeactivate(id)
+	}
+	for id := uint32(1); id <= manager.Workspaces(); id++ {
+		win.SendReload(id, manager.Screen(id))
+	}
+	for _, id := range shown {
+		win.SendActivate(id)
+	}
+	if curr != manager.Curr() || len(hidden) > 0 || len(shown) > 0 {
+		win.SendActivate(manager.Curr())
+	}
+	return nil
+}
 
-	case kbrd.XK_Down:
-		winActive := (key.State & xproto.ModMask4) != 0
-		altActive := (key.State & xproto.ModMask1) != 0
-		if winActive && !altActive {
-			win := NewWindow(uint32(key.Root), manager.Mailbox(), conn)
-			win.SendFocusDown(manager.Curr())
-		}
-		if winActive && altActive {
-			win := NewWindow(uint32(key.Child), manager.Mailbox(), conn)
-			win.SendMoveDown()
-		}
-	case kbrd.XK_q:
-		winActive := (key.State & xproto.ModMask4) != 0
-		if winActive {
-			win := NewWindow(uint32(key.Root), manager.Mailbox(), conn)
-			win.SendClose(manager.Curr())
-		}
-	case kbrd.XK_grave:
-		winActive := (key.State & xproto.ModMask4) != 0
-		if winActive {
-			if _, err := RunCommand(config.LauncherCmd()); err != nil {
-				logging.Error("Application launcher failed")
-			}
+// adoptWindows attaches windows mapped before the window manager
+// started, except the skipped ones, and activates visible workspaces
+func adoptWindows(
+	conn *xgb.Conn, xroot xproto.ScreenInfo,
+	manager *WorkspaceManager, skip map[uint32]bool,
+) error {
+	children, err := xutil.Children(uint32(xroot.Root), conn)
+	if err != nil {
+		return err
+	}
+
+	for _, wid := range children {
+		if skip[wid] || !xutil.IsViewable(wid, conn) {
+			continue
 		}
-		return nil
-	case kbrd.XK_l:
-		winActive := (key.State & xproto.ModMask4) != 0
-		if winActive {
-			if _, err := RunCommand(config.LockerCmd()); err != nil {
-				logging.Error("Locker launch failed")
-			}
+		if xutil.IsDock(wid, conn) {
+			manageDock(wid, conn, manager)
+			continue
 		}
-	default:
-		return nil
+
+		desktop, err := xutil.GetWMDesktop(wid, conn)
+		id := adoptedWorkspace(desktop, err == nil, manager)
+		win := NewWindow(wid, manager.Mailbox(), conn)
+		win.SendAttachWith(id, proto.AttachOptions{Hide: !manager.Visible(id)})
 	}
 
+	activateVisible(conn, manager)
 	return nil
 }
 
-// RunCommand starts specified command in a separate goroutine
+// adoptedWorkspace returns workspace of the window found at startup
+// by its _NET_WM_DESKTOP. The current workspace is used if the window
+// has no desktop or it refers to no workspace, e.g. 0xFFFFFFFF of the
+// sticky windows
+func adoptedWorkspace(desktop uint32, ok bool, manager *WorkspaceManager) uint32 {
+	if !ok || desktop >= manager.Workspaces() {
+		return manager.Curr()
+	}
+	return desktop + 1
+}
+
+// activateVisible activates workspaces shown on the monitors
+func activateVisible(conn *xgb.Conn, manager *WorkspaceManager) {
+	win := NewWindow(0, manager.Mailbox(), conn)
+	for id := uint32(1); id <= manager.Workspaces(); id++ {
+		if id != manager.Curr() && manager.Visible(id) {
+			win.SendActivate(id)
+		}
+	}
+	win.SendActivate(manager.Curr())
+}
+
+// RunCommand starts specified command in a separate goroutine,
+// the command is split into arguments on spaces
 func RunCommand(c string) (*exec.Cmd, error) {
-	args := regexp.MustCompile(" +").Split(c, -1)
+	return RunArgs(regexp.MustCompile(" +").Split(c, -1))
+}
+
+// RunArgs starts program with the arguments in a separate goroutine
+func RunArgs(args []string) (*exec.Cmd, error) {
 	cmd := exec.Command(args[0], args[1:]...)
 	err := cmd.Start()
 	if err != nil {
@@ -250,7 +285,9 @@ func RunCommand(c string) (*exec.Cmd, error) {
 }
 
 func main() {
-	config.ParseArgs()
+	if err := config.ParseArgs(); err != nil {
+		logging.Fatal(err)
+	}
 	logging.Debug = config.Debug()
 
 	conn, err := xgb.NewConn()
@@ -286,10 +323,18 @@ func main() {
 	monitors, err := xutil.ReadMonitorsInfo(conn)
 	if err != nil {
 		logging.Fatal(err)
-	} else if monitors.IsDualSetup() {
-		xutil.SetNumberOfDesktops(MaxWorkspaces, conn)
-	} else {
-		xutil.SetNumberOfDesktops(MaxWorkspaces-1, conn)
+	}
+
+	checkWid, err := xutil.CreateCheckWindow(conn)
+	if err != nil {
+		logging.Fatal(err)
+	}
+	// Restarted process replaces itself, since the X server may still
+	// keep the selection of the closed connection
+	restoring := os.Getenv(restoreEnv) != ""
+	replace := config.Replace() || restoring
+	if err := xutil.AcquireWMSelection(checkWid, replace, conn); err != nil {
+		logging.Fatal(err)
 	}
 
 	if err := xutil.BecomeWM(conn, root); err != nil {
@@ -297,26 +342,65 @@ func main() {
 		logging.Fatal("Cannot take WM ownership")
 	}
 
-	keymap, err := kbrd.Mapping(conn)
-	if err != nil {
+	if err := xutil.GrabMouse(conn, root); err != nil {
 		logging.Fatal(err)
 	}
 
-	if err := xutil.GrabMouse(conn, root); err != nil {
-		logging.Fatal(err)
+	if err := xutil.SelectScreenChanges(conn, root); err != nil {
+		logging.Error("Monitor hotplug is unavailable:", err)
 	}
 
-	if err := xutil.GrabShortcuts(conn, root, keymap); err != nil {
+	bindings := &Bindings{}
+	if err := bindings.Load(conn, root); err != nil {
 		logging.Fatal(err)
 	}
 
 	xutil.SetSupported(conn) // Set EWMH supported atoms
+	if err := xutil.SetCheckWindow(checkWid, Name, conn); err != nil {
+		logging.Error("Creating _NET_SUPPORTING_WM_CHECK window failed:", err)
+	}
 	manager := NewWorkspaceManager(monitors)
+	xutil.SetNumberOfDesktops(manager.Workspaces(), conn)
+	publishWorkArea(conn, manager)
+	clients.OnChange(func(order, stacking []uint32, active uint32) {
+		xutil.SetClientList(order, stacking, conn)
+		xutil.SetActiveWindow(active, conn)
+	})
 
-	for _, cmd := range config.Commands() {
-		c, _ := RunCommand(cmd)
-		defer c.Process.Kill()
+	restored := make(map[uint32]bool)
+	if restoring {
+		restored, err = restoreState(os.Getenv(restoreEnv), conn, manager)
+		if err != nil {
+			logging.Error("Restoring state failed:", err)
+		}
+		os.Unsetenv(restoreEnv)
+	}
+
+	if err := adoptWindows(conn, root, manager, restored); err != nil {
+		logging.Error(err)
 	}
 
-	processEvents(conn, keymap, monitors, manager)
+	if !restoring {
+		for _, cmd := range config.Commands() {
+			c, _ := RunCommand(cmd)
+			defer c.Process.Kill()
+		}
+	}
+
+	requests := make(chan ControlRequest)
+	listener, err := ListenControl(ipc.SocketPath(), requests)
+	if err != nil {
+		logging.Error("Control socket is unavailable:", err)
+	} else {
+		defer listener.Close()
+	}
+
+	if processEvents(conn, root, bindings, manager, requests) == errRestart {
+		if listener != nil {
+			listener.Close()
+		}
+		if err := restart(conn, manager); err != nil {
+			logging.Error("Restart failed:", err)
+		}
+	}
 }
@@ -22,11 +22,26 @@ const (
 	FocusRight
 	FocusUp
 	FocusDown
-	Maximize
+	Fullscreen
 	ResizeLeft
 	ResizeRight
 	Close
 	Exit
+	Reload
+	Query
+	Title
+	Restore
+	Release
+	FocusNext
+	ToggleFloating
+	Hints
+	Configure
+	NewColumn
+	MergeColumn
+	Equalize
+	ResizeUp
+	ResizeDown
+	Layout
 )
 
 // Message represents message of the internal protocol
@@ -35,4 +50,45 @@ type Message struct {
 	To    uint32
 	Type  uint
 	XConn *xgb.Conn
+	// Data holds optional message specific payload
+	Data interface{}
+}
+
+// Actions of the requests changing window state,
+// the values are the same as in _NET_WM_STATE client message
+const (
+	StateRemove = iota
+	StateAdd
+	StateToggle
+)
+
+// DetachOptions holds optional parameters of the Reattach
+// and Detach messages
+type DetachOptions struct {
+	// Window is detached instead of the focused one if it is non-zero
+	Window uint32
+	// Show maps the window after it is attached to the workspace
+	Show bool
+}
+
+// AttachOptions holds optional parameters of the Attach message
+type AttachOptions struct {
+	// Hide is set for already mapped windows attached
+	// to the workspace which isn't visible
+	Hide bool
+	// Column is "left", "right" or the column number counting from 1
+	// to place the window to the specific column, the window is placed
+	// as usual otherwise
+	Column string
+	// Floating windows keep their own geometry above the tiled ones
+	Floating bool
+	// Fullscreen makes the window cover the whole monitor
+	// above other windows of the workspace
+	Fullscreen bool
+	// Parent is the window over which floating window is centered
+	Parent uint32
+	// NoFocus keeps focus on the previously focused window
+	NoFocus bool
+	// Show maps the window without giving it focus
+	Show bool
 }
@@ -0,0 +1,97 @@
+// Package main implements logic of the window manager
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/BurntSushi/xgb"
+	"github.com/Zamony/wmwm/ipc"
+	"github.com/Zamony/wmwm/xutil"
+)
+
+// restoreEnv holds path of the file with the state saved before restart
+const restoreEnv = "WMWM_RESTORE"
+
+// Arrangement describes placement of the windows
+// restored by the Restore message
+type Arrangement struct {
+	State ipc.WorkspaceState
+	Hide  bool
+}
+
+// restart saves state of the workspaces and replaces the window
+// manager process with the new one, which restores the state
+func restart(conn *xgb.Conn, manager *WorkspaceManager) error {
+	data, err := json.Marshal(manager.Tree(conn))
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(os.TempDir(), fmt.Sprintf("wmwm-%d.json", os.Getpid()))
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	conn.Close()
+	os.Setenv(restoreEnv, path)
+	return syscall.Exec(executable, os.Args, os.Environ())
+}
+
+// restoreState places windows listed in the state file back to
+// their workspaces and columns. It returns set of the restored windows
+func restoreState(
+	path string, conn *xgb.Conn, manager *WorkspaceManager,
+) (map[uint32]bool, error) {
+	restored := make(map[uint32]bool)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return restored, err
+	}
+	os.Remove(path)
+
+	var tree ipc.Tree
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return restored, err
+	}
+
+	win := NewWindow(0, manager.Mailbox(), conn)
+	manager.SetVisible(tree.Visible)
+	for _, id := range manager.VisibleAll() {
+		win.SendReload(id, manager.Screen(id))
+	}
+	if tree.Current > 0 && tree.Current <= manager.Workspaces() {
+		manager.SetCurr(tree.Current)
+	}
+
+	for _, state := range tree.Workspaces {
+		if state.ID < 1 || state.ID > manager.Workspaces() {
+			continue
+		}
+
+		for i := range state.Columns {
+			column := &state.Columns[i]
+			windows := column.Windows[:0]
+			for _, w := range column.Windows {
+				if !restored[w.ID] && xutil.Exists(w.ID, conn) {
+					windows = append(windows, w)
+					restored[w.ID] = true
+				}
+			}
+			column.Windows = windows
+		}
+
+		win.SendRestore(state.ID, Arrangement{state, !manager.Visible(state.ID)})
+	}
+
+	return restored, nil
+}
@@ -0,0 +1,84 @@
+// Package main implements logic of the window manager
+package main
+
+import (
+	"github.com/BurntSushi/xgb"
+	"github.com/Zamony/wmwm/config"
+	"github.com/Zamony/wmwm/logging"
+	"github.com/Zamony/wmwm/proto"
+	"github.com/Zamony/wmwm/xutil"
+)
+
+// attachWindow attaches new window to the current workspace
+// or to the one chosen by layout slot or window rule
+func attachWindow(wid uint32, conn *xgb.Conn, manager *WorkspaceManager) {
+	win := NewWindow(wid, manager.Mailbox(), conn)
+	id, options := manager.Curr(), proto.AttachOptions{}
+	if slot, ok := claimSlot(wid, conn); ok {
+		id, options = slot.Workspace, slotOptions(slot.Position)
+	} else if rule, ok := matchRule(wid, conn); ok {
+		if rule.Workspace > 0 && rule.Workspace <= manager.Workspaces() {
+			id = rule.Workspace
+		}
+		options = proto.AttachOptions{
+			Column:     rule.Column,
+			Floating:   rule.Floating,
+			Fullscreen: rule.Fullscreen,
+			NoFocus:    rule.NoFocus,
+		}
+	}
+
+	if xutil.HasWMStateFullscreen(wid, conn) {
+		options.Fullscreen = true
+	}
+	if parent, ok := isFloating(wid, conn); ok {
+		options.Floating, options.Parent = true, parent
+	}
+
+	if options.NoFocus {
+		options.Show = manager.Visible(id)
+		win.SendAttachWith(id, options)
+		return
+	}
+
+	win.SendAttachWith(id, options)
+	if manager.Visible(id) {
+		win.SendActivate(id)
+		manager.SetCurr(id)
+	}
+}
+
+// matchRule returns the first window rule matching the window
+func matchRule(wid uint32, conn *xgb.Conn) (config.Rule, bool) {
+	if len(config.Rules()) < 1 {
+		return config.Rule{}, false
+	}
+
+	instance, class, _ := xutil.GetWMClass(wid, conn)
+	title, _ := xutil.GetWMName(wid, conn)
+	types := xutil.GetWindowTypes(wid, conn)
+	rule, ok := config.MatchRule(instance, class, title, types)
+	if ok {
+		logging.Println("Window", wid, "matches rule", rule.Name)
+	}
+	return rule, ok
+}
+
+// isFloating checks whether the window should float by default:
+// it is transient, has fixed size or is a dialog, splash screen,
+// utility or toolbar. It returns the parent of the transient window
+func isFloating(wid uint32, conn *xgb.Conn) (uint32, bool) {
+	if parent := xutil.GetTransientFor(wid, conn); parent != 0 {
+		return parent, true
+	}
+
+	for _, t := range xutil.GetWindowTypes(wid, conn) {
+		switch t {
+		case "dialog", "splash", "utility", "toolbar":
+			return 0, true
+		}
+	}
+
+	hints, err := xutil.GetSizeHints(wid, conn)
+	return 0, err == nil && hints.IsFixed()
+}
@@ -0,0 +1,120 @@
+// Package session describes named layouts stored on disk.
+// Layout records which applications occupy which columns
+// of which workspaces, applications are identified by WM_CLASS
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Layout describes placement of the applications
+type Layout struct {
+	Workspaces []Workspace `json:"workspaces"`
+}
+
+// Workspace describes columns of the workspace
+type Workspace struct {
+	ID      uint32   `json:"id"`
+	Columns []Column `json:"columns"`
+}
+
+// Column describes windows of the column.
+// Position is the column number counting from 1 or "floating"
+type Column struct {
+	Position string   `json:"position"`
+	Windows  []Window `json:"windows"`
+}
+
+// Window describes application by its WM_CLASS. Args hold command
+// line of the application saved with the layout, they are used to launch
+// the application if it isn't running. Command is split on spaces and
+// used instead if there are no Args, e.g. in layouts written by hand
+type Window struct {
+	Class    string   `json:"class"`
+	Instance string   `json:"instance,omitempty"`
+	Command  string   `json:"command,omitempty"`
+	Args     []string `json:"args,omitempty"`
+}
+
+// Slot is a place in the layout waiting for a window
+type Slot struct {
+	Window
+	Workspace uint32
+	Position  string
+}
+
+// Matches checks whether window with the specified WM_CLASS fits the slot.
+// Instance is compared only if it is specified in the layout
+func (window Window) Matches(instance, class string) bool {
+	if window.Class != class {
+		return false
+	}
+	return window.Instance == "" || window.Instance == instance
+}
+
+// Slots returns places of the layout in the order they are described
+func (layout Layout) Slots() []Slot {
+	var slots []Slot
+	for _, workspace := range layout.Workspaces {
+		for _, column := range workspace.Columns {
+			for _, window := range column.Windows {
+				slots = append(slots, Slot{window, workspace.ID, column.Position})
+			}
+		}
+	}
+	return slots
+}
+
+// Dir returns directory where layouts are stored
+func Dir() string {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "wmwm", "layouts")
+	}
+	return filepath.Join(os.Getenv("HOME"), ".local", "share", "wmwm", "layouts")
+}
+
+// Path returns path of the file holding layout with the specified name
+func Path(name string) (string, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsRune(name, os.PathSeparator) {
+		return "", errors.New("Invalid layout name " + name)
+	}
+	return filepath.Join(Dir(), name+".json"), nil
+}
+
+// Load reads layout with the specified name
+func Load(name string) (Layout, error) {
+	var layout Layout
+	path, err := Path(name)
+	if err != nil {
+		return layout, err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return layout, err
+	}
+	err = json.Unmarshal(data, &layout)
+	return layout, err
+}
+
+// Save writes layout under the specified name
+func Save(name string, layout Layout) error {
+	path, err := Path(name)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(layout, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
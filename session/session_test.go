@@ -0,0 +1,63 @@
+package session
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestSaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wmwm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("XDG_DATA_HOME", dir)
+	defer os.Unsetenv("XDG_DATA_HOME")
+
+	layout := Layout{[]Workspace{{2, []Column{
+		{"left", []Window{{Class: "Firefox", Instance: "Navigator", Command: "firefox"}}},
+		{"right", []Window{{Class: "XTerm"}, {Class: "XTerm", Args: []string{"xterm", "-title", "a b"}}}},
+	}}}}
+	if err := Save("dev", layout); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load("dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	slots := loaded.Slots()
+	if len(slots) != 3 {
+		t.Fatal("Expected 3 slots, got", len(slots))
+	}
+	if s := slots[0]; s.Workspace != 2 || s.Position != "left" || s.Command != "firefox" {
+		t.Error("Wrong slot", s)
+	}
+	if s := slots[2]; !reflect.DeepEqual(s.Args, []string{"xterm", "-title", "a b"}) {
+		t.Error("Arguments aren't kept", s.Args)
+	}
+}
+
+func TestPathInvalidName(t *testing.T) {
+	for _, name := range []string{"", "..", "a/b"} {
+		if _, err := Path(name); err == nil {
+			t.Errorf("Name %q accepted", name)
+		}
+	}
+}
+
+func TestWindowMatches(t *testing.T) {
+	window := Window{Class: "XTerm"}
+	if !window.Matches("xterm", "XTerm") {
+		t.Error("Window without instance doesn't match the class")
+	}
+	window.Instance = "scratch"
+	if window.Matches("xterm", "XTerm") {
+		t.Error("Window matches other instance")
+	}
+	if window.Matches("scratch", "URxvt") {
+		t.Error("Window matches other class")
+	}
+}
@@ -0,0 +1,182 @@
+// Package main implements logic of the window manager
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/BurntSushi/xgb"
+	"github.com/Zamony/wmwm/ipc"
+	"github.com/Zamony/wmwm/logging"
+	"github.com/Zamony/wmwm/proto"
+	"github.com/Zamony/wmwm/session"
+	"github.com/Zamony/wmwm/xutil"
+)
+
+// pendingSlots are places of the restored layout waiting
+// for the windows of the launched applications
+var pendingSlots []session.Slot
+
+// saveLayout describes current placement of the windows
+// by their WM_CLASS and saves it under the specified name
+func saveLayout(name string, conn *xgb.Conn, manager *WorkspaceManager) error {
+	layout := describeLayout(manager.Tree(conn), func(wid uint32) (session.Window, bool) {
+		instance, class, err := xutil.GetWMClass(wid, conn)
+		if err != nil || class == "" {
+			return session.Window{}, false
+		}
+		return session.Window{
+			Class: class, Instance: instance, Args: windowArgs(wid, conn),
+		}, true
+	})
+	return session.Save(name, layout)
+}
+
+// describeLayout builds layout from the state of the workspaces,
+// windows which can't be described are skipped
+func describeLayout(tree ipc.Tree, describe func(wid uint32) (session.Window, bool)) session.Layout {
+	var layout session.Layout
+	for _, ws := range tree.Workspaces {
+		workspace := session.Workspace{ID: ws.ID}
+		for _, col := range ws.Columns {
+			column := session.Column{Position: col.Position}
+			for _, w := range col.Windows {
+				if window, ok := describe(w.ID); ok {
+					column.Windows = append(column.Windows, window)
+				}
+			}
+			if len(column.Windows) > 0 {
+				workspace.Columns = append(workspace.Columns, column)
+			}
+		}
+		if len(workspace.Columns) > 0 {
+			layout.Workspaces = append(layout.Workspaces, workspace)
+		}
+	}
+	return layout
+}
+
+// restoreLayout moves running applications to the places described
+// by the layout with the specified name and launches missing ones
+func restoreLayout(name string, conn *xgb.Conn, manager *WorkspaceManager) error {
+	layout, err := session.Load(name)
+	if err != nil {
+		return err
+	}
+
+	var windows []uint32
+	for _, ws := range manager.Tree(conn).Workspaces {
+		for _, col := range ws.Columns {
+			for _, w := range col.Windows {
+				windows = append(windows, w.ID)
+			}
+		}
+	}
+
+	pendingSlots = nil
+	used := make(map[uint32]bool)
+	for _, slot := range layout.Slots() {
+		if slot.Workspace < 1 || slot.Workspace > manager.Workspaces() {
+			continue
+		}
+
+		found := false
+		for _, wid := range windows {
+			instance, class, err := xutil.GetWMClass(wid, conn)
+			if err != nil || used[wid] || !slot.Matches(instance, class) {
+				continue
+			}
+			used[wid] = true
+			found = true
+			moveWindow(wid, slot.Workspace, slot.Position, conn, manager)
+			break
+		}
+
+		if !found && (len(slot.Args) > 0 || slot.Command != "") {
+			if err := launchSlot(slot); err != nil {
+				logging.Error("Launch of", slot.Class, "failed:", err)
+				continue
+			}
+			pendingSlots = append(pendingSlots, slot)
+		}
+	}
+
+	activateVisible(conn, manager)
+	return nil
+}
+
+// launchSlot starts application of the slot using its saved
+// arguments or its command if there are no arguments
+func launchSlot(slot session.Slot) error {
+	var err error
+	if len(slot.Args) > 0 {
+		_, err = RunArgs(slot.Args)
+	} else {
+		_, err = RunCommand(slot.Command)
+	}
+	return err
+}
+
+// claimSlot finds pending slot for the window and removes it
+func claimSlot(wid uint32, conn *xgb.Conn) (session.Slot, bool) {
+	if len(pendingSlots) < 1 {
+		return session.Slot{}, false
+	}
+
+	instance, class, err := xutil.GetWMClass(wid, conn)
+	if err != nil {
+		return session.Slot{}, false
+	}
+	for i, slot := range pendingSlots {
+		if slot.Matches(instance, class) {
+			pendingSlots = append(pendingSlots[:i], pendingSlots[i+1:]...)
+			return slot, true
+		}
+	}
+	return session.Slot{}, false
+}
+
+// moveWindow moves managed window to the position of the workspace
+func moveWindow(wid, id uint32, position string, conn *xgb.Conn, manager *WorkspaceManager) {
+	hide := !manager.Visible(id) && xutil.IsViewable(wid, conn)
+	win := NewWindow(wid, manager.Mailbox(), conn)
+	options := slotOptions(position)
+	options.Hide = hide
+	options.Fullscreen = xutil.HasWMStateFullscreen(wid, conn)
+	win.SendRelease()
+	win.SendAttachWith(id, options)
+}
+
+// slotOptions returns attach options placing window to the position
+// of the layout slot: the column or the floating windows
+func slotOptions(position string) proto.AttachOptions {
+	if position == ipc.PositionFloating {
+		return proto.AttachOptions{Floating: true}
+	}
+	return proto.AttachOptions{Column: position}
+}
+
+// windowArgs returns command line arguments of the process owning
+// the window, arguments may contain spaces
+func windowArgs(wid uint32, conn *xgb.Conn) []string {
+	pid, err := xutil.GetWMPid(wid, conn)
+	if err != nil {
+		return nil
+	}
+	data, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err != nil {
+		return nil
+	}
+	return parseCmdline(data)
+}
+
+// parseCmdline splits contents of /proc/<pid>/cmdline into arguments
+func parseCmdline(data []byte) []string {
+	data = bytes.TrimRight(data, "\x00")
+	if len(data) < 1 {
+		return nil
+	}
+	return strings.Split(string(data), "\x00")
+}
@@ -0,0 +1,252 @@
+// Package main implements logic of the window manager
+package main
+
+import (
+	"math"
+)
+
+// Names of the layouts. Width of the columns of the column
+// layout is reported to the clients separately, see SizingFull
+const (
+	LayoutColumns = "columns"
+	LayoutMaster  = "master"
+	LayoutMonocle = "monocle"
+	LayoutGrid    = "grid"
+	LayoutSpiral  = "spiral"
+)
+
+// MasterShare is a share of the workspace width
+// taken by the master window of the master layout
+const MasterShare = 0.55
+
+// Rect describes position and size of the screen region
+type Rect struct {
+	X, Y, Width, Height int
+}
+
+// Layout places tiled windows of the workspace. Tiled windows are
+// always kept in the workspace columns, layouts other than the column
+// one treat them as a sequence ordered column by column
+type Layout interface {
+	// Name returns name of the layout
+	Name() string
+	// Add places new tiled window to the workspace
+	Add(workspace *Workspace, window *Window)
+	// Remove removes tiled window from the workspace
+	Remove(workspace *Workspace, window *Window)
+	// Arrange places tiled windows within the area and returns
+	// windows whose minimum size doesn't fit their slots
+	Arrange(workspace *Workspace, area Rect) []*Window
+}
+
+// layouts lists available layouts in order of cycling,
+// the first one is used by default
+var layouts = []Layout{
+	columnLayout{},
+	tileLayout{LayoutMaster, masterTiles},
+	tileLayout{LayoutMonocle, monocleTiles},
+	tileLayout{LayoutGrid, gridTiles},
+	tileLayout{LayoutSpiral, spiralTiles},
+}
+
+// LayoutByName returns layout with the specified name
+func LayoutByName(name string) (Layout, bool) {
+	for _, layout := range layouts {
+		if layout.Name() == name {
+			return layout, true
+		}
+	}
+	return nil, false
+}
+
+// nextLayout returns layout following the specified one
+func nextLayout(layout Layout) Layout {
+	for i := range layouts {
+		if layouts[i].Name() == layout.Name() {
+			return layouts[(i+1)%len(layouts)]
+		}
+	}
+	return layouts[0]
+}
+
+// columnLayout places windows in columns of adjustable width,
+// windows of a column share its height according to their weights
+type columnLayout struct{}
+
+// Name returns name of the layout
+func (columnLayout) Name() string {
+	return LayoutColumns
+}
+
+// Add adds new window to the workspace. The second window gets its
+// own column, additional windows are placed in the last column
+func (columnLayout) Add(workspace *Workspace, window *Window) {
+	switch len(workspace.columns) {
+	case 0:
+		workspace.splitColumn(-1).Add(window)
+	case 1:
+		workspace.splitColumn(0).Add(window)
+	default:
+		workspace.columns[len(workspace.columns)-1].Add(window)
+	}
+}
+
+// Remove removes window from its column,
+// column left without windows is removed
+func (columnLayout) Remove(workspace *Workspace, window *Window) {
+	if i, _ := workspace.position(window.Id()); i > -1 {
+		workspace.removeFromColumn(i, window)
+	}
+}
+
+// Arrange places columns and their windows within the area
+// dividing its width according to the column ratios
+func (columnLayout) Arrange(workspace *Workspace, area Rect) []*Window {
+	var sum float64
+	for _, column := range workspace.columns {
+		sum += column.Ratio()
+	}
+
+	var unfit []*Window
+	x := 0
+	for i, column := range workspace.columns {
+		w := int(math.Round(float64(area.Width) * column.Ratio() / sum))
+		if i == len(workspace.columns)-1 {
+			w = area.Width - x
+		}
+		r := Rect{area.X + x, area.Y, w, area.Height}
+		unfit = append(unfit, column.Place(r)...)
+		x += w
+	}
+	return unfit
+}
+
+// tileLayout places the sequence of tiled windows
+// into the slots computed by the tiles function
+type tileLayout struct {
+	name  string
+	tiles func(n int, area Rect) []Rect
+}
+
+// Name returns name of the layout
+func (layout tileLayout) Name() string {
+	return layout.name
+}
+
+// Add appends window to the end of the sequence
+func (layout tileLayout) Add(workspace *Workspace, window *Window) {
+	if len(workspace.columns) < 1 {
+		workspace.splitColumn(-1).Add(window)
+		return
+	}
+	workspace.columns[len(workspace.columns)-1].Add(window)
+}
+
+// Remove removes window from the sequence
+func (layout tileLayout) Remove(workspace *Workspace, window *Window) {
+	columnLayout{}.Remove(workspace, window)
+}
+
+// Arrange places windows into their slots. Fullscreen windows
+// keep their slots, but aren't placed
+func (layout tileLayout) Arrange(workspace *Workspace, area Rect) []*Window {
+	windows := workspace.Tiled()
+	var unfit []*Window
+	for i, r := range layout.tiles(len(windows), area) {
+		win := windows[i]
+		switch {
+		case win.IsFullscreen():
+		case win.Fits(r.Width, r.Height):
+			win.Place(r.X, r.Y, r.Width, r.Height)
+		default:
+			unfit = append(unfit, win)
+		}
+	}
+	return unfit
+}
+
+// masterTiles gives the left part of the area to the first window,
+// other windows share height of the right part
+func masterTiles(n int, area Rect) []Rect {
+	if n < 2 {
+		return monocleTiles(n, area)
+	}
+	w := int(math.Round(float64(area.Width) * MasterShare))
+	stack := Rect{area.X + w, area.Y, area.Width - w, area.Height}
+	tiles := []Rect{{area.X, area.Y, w, area.Height}}
+	return append(tiles, rowTiles(n-1, stack, false)...)
+}
+
+// monocleTiles gives the whole area to every window
+func monocleTiles(n int, area Rect) []Rect {
+	tiles := make([]Rect, n)
+	for i := range tiles {
+		tiles[i] = area
+	}
+	return tiles
+}
+
+// gridTiles places windows in rows of equal height, there are as many
+// columns as rows or one more. Windows of the last row share its width
+func gridTiles(n int, area Rect) []Rect {
+	if n < 1 {
+		return nil
+	}
+	cols := int(math.Ceil(math.Sqrt(float64(n))))
+	rows := (n + cols - 1) / cols
+	var tiles []Rect
+	for i, row := range rowTiles(rows, area, false) {
+		count := minInt(cols, n-i*cols)
+		tiles = append(tiles, rowTiles(count, row, true)...)
+	}
+	return tiles
+}
+
+// spiralTiles gives every window half of the area left by the previous
+// ones turning clockwise: left, top, right and bottom halves in turn
+func spiralTiles(n int, area Rect) []Rect {
+	var tiles []Rect
+	rest := area
+	for i := 0; i < n-1; i++ {
+		tile := rest
+		switch i % 4 {
+		case 0:
+			tile.Width = rest.Width / 2
+			rest.X, rest.Width = rest.X+tile.Width, rest.Width-tile.Width
+		case 1:
+			tile.Height = rest.Height / 2
+			rest.Y, rest.Height = rest.Y+tile.Height, rest.Height-tile.Height
+		case 2:
+			tile.Width = rest.Width / 2
+			tile.X = rest.X + rest.Width - tile.Width
+			rest.Width -= tile.Width
+		case 3:
+			tile.Height = rest.Height / 2
+			tile.Y = rest.Y + rest.Height - tile.Height
+			rest.Height -= tile.Height
+		}
+		tiles = append(tiles, tile)
+	}
+	if n > 0 {
+		tiles = append(tiles, rest)
+	}
+	return tiles
+}
+
+// rowTiles divides the area into n equal parts placed
+// side by side if horizontal is set or one under another
+func rowTiles(n int, area Rect, horizontal bool) []Rect {
+	tiles := make([]Rect, n)
+	for i := range tiles {
+		tile := area
+		if horizontal {
+			tile.X = area.X + area.Width*i/n
+			tile.Width = area.X + area.Width*(i+1)/n - tile.X
+		} else {
+			tile.Y = area.Y + area.Height*i/n
+			tile.Height = area.Y + area.Height*(i+1)/n - tile.Y
+		}
+		tiles[i] = tile
+	}
+	return tiles
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/BurntSushi/xgb"
 	"github.com/BurntSushi/xgb/xproto"
 	"github.com/Zamony/wmwm/config"
+	"github.com/Zamony/wmwm/ipc"
 	"github.com/Zamony/wmwm/logging"
 	"github.com/Zamony/wmwm/proto"
 	"github.com/Zamony/wmwm/xutil"
@@ -20,11 +21,20 @@ type Window struct {
 	id             uint32
 	conn           *xgb.Conn
 	removalAllowed bool
+	floating       bool
+	fullscreen     bool
+	// saved holds geometry of floating window before fullscreen mode
+	saved [4]int
+	hints xutil.SizeHints
+	// weight is a share of the column height taken by the window
+	weight float64
 }
 
 // NewWindow creates instance of Window
 func NewWindow(id uint32, c chan proto.Message, xc *xgb.Conn) *Window {
-	return &Window{0, 0, 0, 0, c, id, xc, true}
+	return &Window{
+		mailbox: c, id: id, conn: xc, removalAllowed: true, weight: 1,
+	}
 }
 
 // Id returns identifier of window
@@ -32,133 +42,278 @@ func (window *Window) Id() uint32 {
 	return window.id
 }
 
+// Weight returns share of the column height taken by the window
+func (window *Window) Weight() float64 {
+	return window.weight
+}
+
+// SetWeight sets share of the column height taken by the window
+func (window *Window) SetWeight(weight float64) {
+	window.weight = weight
+}
+
 // SendAttach sends attach request to the specified workspace
 func (window *Window) SendAttach(to uint32) {
-	msg := proto.Message{window.id, to, proto.Attach, window.conn}
+	msg := proto.Message{From: window.id, To: to, Type: proto.Attach, XConn: window.conn}
+	window.mailbox <- msg
+}
+
+// SendAttachWith sends attach request with options to the specified workspace
+func (window *Window) SendAttachWith(to uint32, options proto.AttachOptions) {
+	msg := proto.Message{From: window.id, To: to, Type: proto.Attach, XConn: window.conn, Data: options}
 	window.mailbox <- msg
 }
 
 // SendDetach sends detach request to the specified workspace
 func (window *Window) SendDetach(to uint32) {
-	msg := proto.Message{window.id, to, proto.Detach, window.conn}
+	msg := proto.Message{From: window.id, To: to, Type: proto.Detach, XConn: window.conn}
 	window.mailbox <- msg
 }
 
 // SendReattach sends reattach request to the specified workspace
 func (window *Window) SendReattach(to uint32) {
-	msg := proto.Message{window.id, to, proto.Reattach, window.conn}
+	msg := proto.Message{From: window.id, To: to, Type: proto.Reattach, XConn: window.conn}
+	window.mailbox <- msg
+}
+
+// SendReattachWith sends reattach request with options to the specified workspace
+func (window *Window) SendReattachWith(to uint32, options proto.DetachOptions) {
+	msg := proto.Message{From: window.id, To: to, Type: proto.Reattach, XConn: window.conn, Data: options}
+	window.mailbox <- msg
+}
+
+// SendDetachWith sends detach request to the specified workspace,
+// data holds proto.DetachOptions if there are any
+func (window *Window) SendDetachWith(to uint32, data interface{}) {
+	msg := proto.Message{From: window.id, To: to, Type: proto.Detach, XConn: window.conn, Data: data}
 	window.mailbox <- msg
 }
 
 // SendDeactivate sends request to deactivate specified workspace
 func (window *Window) SendDeactivate(to uint32) {
-	msg := proto.Message{window.id, to, proto.Deactivate, window.conn}
+	msg := proto.Message{From: window.id, To: to, Type: proto.Deactivate, XConn: window.conn}
 	window.mailbox <- msg
 }
 
 // SendActivate sends request to activate specified workspace
 func (window *Window) SendActivate(id uint32) {
-	msg := proto.Message{window.id, id, proto.Activate, window.conn}
+	msg := proto.Message{From: window.id, To: id, Type: proto.Activate, XConn: window.conn}
 	window.mailbox <- msg
 }
 
 // SendRemove sends "remove me" request to workspace which it belongs to
 func (window *Window) SendRemove() {
-	msg := proto.Message{window.id, 0, proto.Remove, window.conn}
+	msg := proto.Message{From: window.id, To: 0, Type: proto.Remove, XConn: window.conn}
 	window.mailbox <- msg
 }
 
-// SendMoveLeft sends "move me to the left" request
-// to workspace which it belongs to
-func (window *Window) SendMoveLeft() {
-	msg := proto.Message{window.id, 0, proto.MoveLeft, window.conn}
+// SendRelease sends request to remove the window from its workspace
+// without unmapping, so it can be attached to another workspace
+func (window *Window) SendRelease() {
+	msg := proto.Message{From: window.id, To: 0, Type: proto.Release, XConn: window.conn}
 	window.mailbox <- msg
 }
 
-// SendMoveRight sends "move me to the right" request
-// to workspace which it belongs to
-func (window *Window) SendMoveRight() {
-	msg := proto.Message{window.id, 0, proto.MoveRight, window.conn}
+// SendMoveLeft sends request to move focused window to the left
+// to the specified workspace
+func (window *Window) SendMoveLeft(id uint32) {
+	msg := proto.Message{From: window.id, To: id, Type: proto.MoveLeft, XConn: window.conn}
 	window.mailbox <- msg
 }
 
-// SendMoveUp sends "move me to the up" request
-// to workspace which it belongs to
-func (window *Window) SendMoveUp() {
-	msg := proto.Message{window.id, 0, proto.MoveUp, window.conn}
+// SendMoveRight sends request to move focused window to the right
+// to the specified workspace
+func (window *Window) SendMoveRight(id uint32) {
+	msg := proto.Message{From: window.id, To: id, Type: proto.MoveRight, XConn: window.conn}
 	window.mailbox <- msg
 }
 
-// SendMoveDown sends "move me to the down" request
-// to workspace which it belongs to
-func (window *Window) SendMoveDown() {
-	msg := proto.Message{window.id, 0, proto.MoveDown, window.conn}
+// SendMoveUp sends request to move focused window up
+// to the specified workspace
+func (window *Window) SendMoveUp(id uint32) {
+	msg := proto.Message{From: window.id, To: id, Type: proto.MoveUp, XConn: window.conn}
+	window.mailbox <- msg
+}
+
+// SendMoveDown sends request to move focused window down
+// to the specified workspace
+func (window *Window) SendMoveDown(id uint32) {
+	msg := proto.Message{From: window.id, To: id, Type: proto.MoveDown, XConn: window.conn}
 	window.mailbox <- msg
 }
 
 // SendReattach sends close request to the specified workspace
 func (window *Window) SendClose(id uint32) {
-	msg := proto.Message{window.id, id, proto.Close, window.conn}
+	msg := proto.Message{From: window.id, To: id, Type: proto.Close, XConn: window.conn}
+	window.mailbox <- msg
+}
+
+// SendCloseWindow sends request to the specified workspace
+// to close the window with wid identifier
+func (window *Window) SendCloseWindow(id, wid uint32) {
+	msg := proto.Message{From: window.id, To: id, Type: proto.Close, XConn: window.conn, Data: wid}
 	window.mailbox <- msg
 }
 
 // SendExit broadcasts exit message
 func (window *Window) SendExit() {
-	msg := proto.Message{window.id, 0, proto.Exit, window.conn}
+	msg := proto.Message{From: window.id, To: 0, Type: proto.Exit, XConn: window.conn}
 	window.mailbox <- msg
 }
 
 // SendFocusHere sends "focus on me" request
 // to workspace which it belongs to
 func (window *Window) SendFocusHere() {
-	msg := proto.Message{window.id, 0, proto.FocusHere, window.conn}
+	msg := proto.Message{From: window.id, To: 0, Type: proto.FocusHere, XConn: window.conn}
 	window.mailbox <- msg
 }
 
 // SendFocusLeft sends "focus on the window on the left from the current focus"
 // request to workspace which it belongs to
 func (window *Window) SendFocusLeft(id uint32) {
-	msg := proto.Message{window.id, id, proto.FocusLeft, window.conn}
+	msg := proto.Message{From: window.id, To: id, Type: proto.FocusLeft, XConn: window.conn}
 	window.mailbox <- msg
 }
 
 // SendFocusRight sends "focus on the window on the right from the current focus"
 // request to workspace which it belongs to
 func (window *Window) SendFocusRight(id uint32) {
-	msg := proto.Message{window.id, id, proto.FocusRight, window.conn}
+	msg := proto.Message{From: window.id, To: id, Type: proto.FocusRight, XConn: window.conn}
 	window.mailbox <- msg
 }
 
 // SendFocusUp sends "focus on the window which is above current focus"
 // request to workspace which it belongs to
 func (window *Window) SendFocusUp(id uint32) {
-	msg := proto.Message{window.id, id, proto.FocusUp, window.conn}
+	msg := proto.Message{From: window.id, To: id, Type: proto.FocusUp, XConn: window.conn}
 	window.mailbox <- msg
 }
 
 // SendFocusDown sends "focus on the window which is under current focus"
 // request to workspace which it belongs to
 func (window *Window) SendFocusDown(id uint32) {
-	msg := proto.Message{window.id, id, proto.FocusDown, window.conn}
+	msg := proto.Message{From: window.id, To: id, Type: proto.FocusDown, XConn: window.conn}
+	window.mailbox <- msg
+}
+
+// SendFocusNext sends request to the specified workspace
+// to focus the next window, floating ones included
+func (window *Window) SendFocusNext(id uint32) {
+	msg := proto.Message{From: window.id, To: id, Type: proto.FocusNext, XConn: window.conn}
+	window.mailbox <- msg
+}
+
+// SendToggleFloating sends request to the specified workspace
+// to switch focused window between tiled and floating
+func (window *Window) SendToggleFloating(id uint32) {
+	msg := proto.Message{From: window.id, To: id, Type: proto.ToggleFloating, XConn: window.conn}
+	window.mailbox <- msg
+}
+
+// SendNewColumn sends request to the specified workspace to move
+// focused window to the new column placed to the right of its column
+func (window *Window) SendNewColumn(id uint32) {
+	msg := proto.Message{From: window.id, To: id, Type: proto.NewColumn, XConn: window.conn}
 	window.mailbox <- msg
 }
 
-// SendMaximize sends request to the specified workspace,
-// which makes central column full in size
-func (window *Window) SendMaximize(id uint32) {
-	msg := proto.Message{window.id, id, proto.Maximize, window.conn}
+// SendMergeColumn sends request to the specified workspace
+// to merge column of the focused window into its neighbour
+func (window *Window) SendMergeColumn(id uint32) {
+	msg := proto.Message{From: window.id, To: id, Type: proto.MergeColumn, XConn: window.conn}
+	window.mailbox <- msg
+}
+
+// SendHints sends notification about changed size hints of the window
+func (window *Window) SendHints() {
+	msg := proto.Message{From: window.id, To: 0, Type: proto.Hints, XConn: window.conn}
+	window.mailbox <- msg
+}
+
+// SendConfigure passes configure request of the window
+// to the specified workspace
+func (window *Window) SendConfigure(id uint32, request xproto.ConfigureRequestEvent) {
+	msg := proto.Message{From: window.id, To: id, Type: proto.Configure, XConn: window.conn, Data: request}
+	window.mailbox <- msg
+}
+
+// SendFullscreen sends request to the specified workspace,
+// which toggles fullscreen mode of the window
+func (window *Window) SendFullscreen(id uint32) {
+	msg := proto.Message{From: window.id, To: id, Type: proto.Fullscreen, XConn: window.conn}
+	window.mailbox <- msg
+}
+
+// SendFullscreenState sends request to the specified workspace to enable,
+// disable or toggle fullscreen mode of the window, see proto.StateAdd
+func (window *Window) SendFullscreenState(id uint32, action int) {
+	msg := proto.Message{From: window.id, To: id, Type: proto.Fullscreen, XConn: window.conn, Data: action}
 	window.mailbox <- msg
 }
 
 // SendResizeLeft sends request to resize current window to the left
 func (window *Window) SendResizeLeft(id uint32) {
-	msg := proto.Message{window.id, id, proto.ResizeLeft, window.conn}
+	msg := proto.Message{From: window.id, To: id, Type: proto.ResizeLeft, XConn: window.conn}
 	window.mailbox <- msg
 }
 
 // SendResizeRight sends request to resize current window to the right
 func (window *Window) SendResizeRight(id uint32) {
-	msg := proto.Message{window.id, id, proto.ResizeRight, window.conn}
+	msg := proto.Message{From: window.id, To: id, Type: proto.ResizeRight, XConn: window.conn}
+	window.mailbox <- msg
+}
+
+// SendResizeUp sends request to make current window taller
+func (window *Window) SendResizeUp(id uint32) {
+	msg := proto.Message{From: window.id, To: id, Type: proto.ResizeUp, XConn: window.conn}
+	window.mailbox <- msg
+}
+
+// SendResizeDown sends request to make current window shorter
+func (window *Window) SendResizeDown(id uint32) {
+	msg := proto.Message{From: window.id, To: id, Type: proto.ResizeDown, XConn: window.conn}
+	window.mailbox <- msg
+}
+
+// SendLayout sends request to the specified workspace to use the
+// layout with the given name, the next layout is used if it is empty
+func (window *Window) SendLayout(id uint32, name string) {
+	msg := proto.Message{From: window.id, To: id, Type: proto.Layout, XConn: window.conn, Data: name}
+	window.mailbox <- msg
+}
+
+// SendEqualize sends request to the specified workspace to make
+// its columns equally wide and windows of every column equally tall
+func (window *Window) SendEqualize(id uint32) {
+	msg := proto.Message{From: window.id, To: id, Type: proto.Equalize, XConn: window.conn}
+	window.mailbox <- msg
+}
+
+// SendReload sends request to the specified workspace
+// to re-apply configuration using the given screen
+func (window *Window) SendReload(id uint32, screen xutil.Screen) {
+	msg := proto.Message{From: window.id, To: id, Type: proto.Reload, XConn: window.conn, Data: screen}
+	window.mailbox <- msg
+}
+
+// SendTitle notifies workspace which the window belongs to
+// that the title of the window has changed
+func (window *Window) SendTitle() {
+	msg := proto.Message{From: window.id, To: 0, Type: proto.Title, XConn: window.conn}
+	window.mailbox <- msg
+}
+
+// SendRestore sends request to the specified workspace
+// to place windows according to the arrangement
+func (window *Window) SendRestore(id uint32, arrangement Arrangement) {
+	msg := proto.Message{From: window.id, To: id, Type: proto.Restore, XConn: window.conn, Data: arrangement}
+	window.mailbox <- msg
+}
+
+// SendQuery sends request to the specified workspace
+// to reply with its state to the channel
+func (window *Window) SendQuery(id uint32, reply chan ipc.WorkspaceState) {
+	msg := proto.Message{From: window.id, To: id, Type: proto.Query, XConn: window.conn, Data: reply}
 	window.mailbox <- msg
 }
 
@@ -204,6 +359,16 @@ func (window *Window) Unmap() error {
 	return xutil.UnmapWindow(window.id, window.conn)
 }
 
+// Hide unmaps the window. Following UnmapNotify event
+// won't cause removal of the window from the workspace
+func (window *Window) Hide() error {
+	if err := xutil.WatchWindowEvents(window.id, window.conn); err != nil {
+		return err
+	}
+	window.DenyRemoval()
+	return window.Unmap()
+}
+
 // Close closes the window
 func (window *Window) Close() error {
 	return xutil.SendClientEvent(
@@ -233,6 +398,9 @@ func (window *Window) Defocus() error {
 // UnsetBorder removes padding to make the window
 // looks like it doesn't has border
 func (window *Window) UnsetBorder() error {
+	if window.floating || window.fullscreen {
+		return nil
+	}
 	return xutil.RemovePaddingFromWindow(
 		window.y, window.height, config.BorderWidth(),
 		window.id, window.conn,
@@ -241,6 +409,9 @@ func (window *Window) UnsetBorder() error {
 
 // SetBorder adds padding to make the window looks like it has border
 func (window *Window) SetBorder() error {
+	if window.floating || window.fullscreen {
+		return nil
+	}
 	return xutil.AddPaddingToWindow(
 		window.y, window.height, config.BorderWidth(),
 		window.id, window.conn,
@@ -272,9 +443,114 @@ func (window *Window) TakeFocus() error {
 		return err
 	}
 
+	clients.SetActive(window.id)
 	return xutil.FocusWindow(window.id, window.conn)
 }
 
+// SetFloating makes the window keep its own geometry
+// instead of being tiled
+func (window *Window) SetFloating() error {
+	window.floating = true
+	x, y, w, h, err := xutil.GetGeometry(window.id, window.conn)
+	if err != nil {
+		return err
+	}
+	window.x, window.y, window.width, window.height = x, y, w, h
+	return nil
+}
+
+// SetTiled makes the window fit the column
+func (window *Window) SetTiled() {
+	window.floating = false
+}
+
+// LoadHints reads and caches size constraints of the window
+func (window *Window) LoadHints() {
+	hints, err := xutil.GetSizeHints(window.id, window.conn)
+	if err != nil {
+		hints = xutil.SizeHints{}
+	}
+	window.hints = hints
+}
+
+// Fits checks whether minimum size of the window fits the slot
+func (window *Window) Fits(width, height int) bool {
+	return window.hints.MinWidth <= width && window.hints.MinHeight <= height
+}
+
+// Place fits the window into the slot honoring its size hints.
+// The window is centered in the slot if it can't fill it entirely
+func (window *Window) Place(x, y, width, height int) error {
+	w, h := window.hints.Constrain(width, height)
+	return window.Move(x+(width-w)/2, y+(height-h)/2, w, h)
+}
+
+// Configure applies configure request to the floating window.
+// Border width is ignored, since windows don't have borders
+func (window *Window) Configure(request xproto.ConfigureRequestEvent) error {
+	mask := request.ValueMask &^ xproto.ConfigWindowBorderWidth
+	if mask&xproto.ConfigWindowX != 0 {
+		window.x = int(request.X)
+	}
+	if mask&xproto.ConfigWindowY != 0 {
+		window.y = int(request.Y)
+	}
+	if mask&xproto.ConfigWindowWidth != 0 {
+		window.width = int(request.Width)
+	}
+	if mask&xproto.ConfigWindowHeight != 0 {
+		window.height = int(request.Height)
+	}
+	return xutil.ConfigureFromRequest(request, mask, window.conn)
+}
+
+// NotifyGeometry tells the client its actual geometry
+// instead of the requested one
+func (window *Window) NotifyGeometry() error {
+	return xutil.SendConfigureNotify(
+		window.x, window.y, window.width, window.height, window.id, window.conn,
+	)
+}
+
+// Move changes position and size of the window
+func (window *Window) Move(x, y, width, height int) error {
+	window.x, window.y, window.width, window.height = x, y, width, height
+	return xutil.MoveResizeWindow(x, y, width, height, window.id, window.conn)
+}
+
+// SetFullscreen enables or disables fullscreen mode
+// and updates _NET_WM_STATE of the window accordingly
+func (window *Window) SetFullscreen(enabled bool) error {
+	if window.fullscreen == enabled {
+		return nil
+	}
+
+	window.fullscreen = enabled
+	if enabled {
+		window.saved = [4]int{window.x, window.y, window.width, window.height}
+	} else if window.floating {
+		saved := window.saved
+		window.Move(saved[0], saved[1], saved[2], saved[3])
+	}
+	return xutil.SetWMStateFullscreen(window.id, enabled, window.conn)
+}
+
+// IsFullscreen checks whether the window is in fullscreen mode
+func (window Window) IsFullscreen() bool {
+	return window.fullscreen
+}
+
+// IsFloating checks whether the window is floating
+func (window Window) IsFloating() bool {
+	return window.floating
+}
+
+// Raise puts the window above other windows
+func (window *Window) Raise() error {
+	clients.Raise(window.id)
+	return xutil.RaiseWindow(window.id, window.conn)
+}
+
 // DenyRemoval sets a flag that will forbid window removal
 func (window *Window) DenyRemoval() {
 	window.removalAllowed = false
@@ -290,9 +566,25 @@ func (window Window) IsRemovalAllowed() bool {
 	return window.removalAllowed
 }
 
-// IsDock performs check whether this window is dock or not
-func (window Window) IsDock() bool {
-	return xutil.IsDock(window.Id(), window.conn)
+// State returns description of the window
+func (window *Window) State(focused bool) ipc.WindowState {
+	var title string
+	if window.conn != nil {
+		title, _ = xutil.GetWMName(window.id, window.conn)
+	}
+	return ipc.WindowState{
+		ID:      window.id,
+		Title:   title,
+		X:       window.x,
+		Y:       window.y,
+		Width:   window.width,
+		Height:  window.height,
+		Focused: focused,
+		Weight:  window.weight,
+
+		Floating:   window.floating,
+		Fullscreen: window.fullscreen,
+	}
 }
 
 // LogStatus logs window's information for debugging purposes
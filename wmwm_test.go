@@ -1,14 +1,45 @@
 package main
 
 import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/BurntSushi/xgb"
 	"github.com/BurntSushi/xgb/xproto"
+	"github.com/Zamony/wmwm/config"
+	"github.com/Zamony/wmwm/ipc"
 	"github.com/Zamony/wmwm/proto"
+	"github.com/Zamony/wmwm/session"
 	"github.com/Zamony/wmwm/xutil"
 )
 
+// configureCall records request made by ConfigureWindowChecked
+type configureCall struct {
+	Window uint32
+	Mask   uint16
+	Values []uint32
+}
+
+// stubConfigure replaces ConfigureWindowChecked for the duration
+// of the test and returns requests made through it
+func stubConfigure(t *testing.T) *[]configureCall {
+	calls := &[]configureCall{}
+	old := xutil.ConfigureWindowChecked
+	t.Cleanup(func() { xutil.ConfigureWindowChecked = old })
+	cookie := &xgb.Cookie{}
+	xutil.ConfigureWindowChecked = func(
+		c *xgb.Conn, window xproto.Window, ValueMask uint16, ValueList []uint32,
+	) xproto.ConfigureWindowCookie {
+		*calls = append(*calls, configureCall{uint32(window), ValueMask, ValueList})
+		return xproto.ConfigureWindowCookie{Cookie: cookie}
+	}
+	return calls
+}
+
 func TestColumnIndexById(t *testing.T) {
 	screen := xutil.NewScreen(4, 3, 0, 0, 0)
 	c := NewColumn(screen)
@@ -122,13 +153,7 @@ func TestColumnReshape(t *testing.T) {
 	c.Add(w1)
 	c.Add(w2)
 	c.Add(w3)
-	oldConfWC := xutil.ConfigureWindowChecked
-	cookie := &xgb.Cookie{}
-	xutil.ConfigureWindowChecked = func(
-		c *xgb.Conn, window xproto.Window, ValueMask uint16, ValueList []uint32,
-	) xproto.ConfigureWindowCookie {
-		return xproto.ConfigureWindowCookie{cookie}
-	}
+	stubConfigure(t)
 	c.Reshape()
 	if w1.y != 0 || w1.height != 1 {
 		t.Error("W1 has invalid geometry", w1.y, w1.height)
@@ -140,7 +165,104 @@ func TestColumnReshape(t *testing.T) {
 		t.Error("W3 has invalid geometry", w3.y, w3.height)
 	}
 
-	xutil.ConfigureWindowChecked = oldConfWC
+}
+
+func TestColumnReshapeHints(t *testing.T) {
+	stubConfigure(t)
+
+	screen := xutil.NewScreen(100, 60, 0, 0, 0)
+	c := NewColumn(screen)
+	ch := make(chan proto.Message)
+	w1 := NewWindow(1, ch, nil)
+	w1.hints = xutil.SizeHints{WidthInc: 7, HeightInc: 8, BaseWidth: 2, BaseHeight: 2}
+	w2 := NewWindow(2, ch, nil)
+	w2.hints = xutil.SizeHints{MinWidth: 10, MinHeight: 40}
+	c.Add(w1)
+	c.Add(w2)
+	unfit := c.Reshape()
+	if len(unfit) != 1 || unfit[0] != w2 {
+		t.Error("Window with large minimum size fits the column")
+	}
+	if w1.width != 100 || w1.height != 26 || w1.y != 2 {
+		t.Error("W1 doesn't respect increments", w1.width, w1.height, w1.y)
+	}
+}
+
+func TestWorkspaceReshapeFullscreen(t *testing.T) {
+	calls := stubConfigure(t)
+
+	c := make(chan proto.Message)
+	screen := xutil.NewScreen(80, 60, 0, 5, 5)
+	wr := NewWorkspace(c, c, nil, 1, screen)
+	w1 := NewWindow(1, c, nil)
+	w2 := NewWindow(2, c, nil)
+	wr.Add(w1)
+	wr.Add(w2)
+	w2.fullscreen = true
+	wr.Reshape()
+	if w2.x != 0 || w2.y != 0 || w2.width != 80 || w2.height != 60 {
+		t.Error("Fullscreen window doesn't cover the monitor", w2.x, w2.y, w2.width, w2.height)
+	}
+	if w1.width != 40 || w1.y != 5 {
+		t.Error("Tiled window lost its slot", w1.width, w1.y)
+	}
+
+	wr.focus = w1
+	*calls = nil
+	wr.raiseFocus()
+	if raised := raisedWindows(*calls); len(raised) > 0 {
+		t.Error("Focused tile shouldn't be raised above the fullscreen window", raised)
+	}
+
+	monocle, _ := LayoutByName(LayoutMonocle)
+	wr.SetLayout(monocle)
+	*calls = nil
+	wr.raiseFocus()
+	if raised := raisedWindows(*calls); !reflect.DeepEqual(raised, []uint32{1, 2}) {
+		t.Error("Fullscreen window should stay above the focused tile", raised)
+	}
+}
+
+// raisedWindows returns windows raised by the configure requests in order
+func raisedWindows(calls []configureCall) []uint32 {
+	var raised []uint32
+	for _, call := range calls {
+		if call.Mask == xproto.ConfigWindowStackMode {
+			raised = append(raised, call.Window)
+		}
+	}
+	return raised
+}
+
+func TestParseSizeHints(t *testing.T) {
+	value := make([]byte, 18*4)
+	put := func(i int, v uint32) { xgb.Put32(value[i*4:], v) }
+	put(0, 1<<4|1<<5|1<<6) // Minimum and maximum sizes, increments
+	put(5, 20)
+	put(6, 10)
+	put(7, 20)
+	put(8, 10)
+	put(9, 3)
+	put(10, 5)
+	hints, err := xutil.ParseSizeHints(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hints.IsFixed() || hints.BaseWidth != 20 || hints.HeightInc != 5 {
+		t.Error("Wrong hints", hints)
+	}
+	if w, h := hints.Constrain(50, 50); w != 20 || h != 10 {
+		t.Error("Maximum size isn't respected", w, h)
+	}
+}
+
+// columnLens returns the number of windows in every column
+func columnLens(wr *Workspace) []int {
+	lens := make([]int, 0, len(wr.columns))
+	for _, column := range wr.columns {
+		lens = append(lens, column.Len())
+	}
+	return lens
 }
 
 func TestWorkspaceAdd(t *testing.T) {
@@ -149,29 +271,22 @@ func TestWorkspaceAdd(t *testing.T) {
 	wr := NewWorkspace(c, c, nil, 1, screen)
 	w1 := NewWindow(1, c, nil)
 	wr.Add(w1)
-	if wr.central.Len() != 1 {
-		t.Error("Win1: wr.central.Len() != 1")
+	if lens := columnLens(wr); !reflect.DeepEqual(lens, []int{1}) {
+		t.Error("Win1: wrong columns", lens)
 	}
 	w2 := NewWindow(2, c, nil)
 	wr.Add(w2)
-	switch {
-	case wr.central.Len() != 0:
-		t.Error("Win2: wr.central.Len() != 0")
-	case wr.left.Len() != 1:
-		t.Error("Win2: wr.left.Len() != 1")
-	case wr.right.Len() != 1:
-		t.Error("Win2: wr.right.Len() != 1")
+	if lens := columnLens(wr); !reflect.DeepEqual(lens, []int{1, 1}) {
+		t.Error("Win2: wrong columns", lens)
 	}
 
 	w3 := NewWindow(3, c, nil)
 	wr.Add(w3)
-	switch {
-	case wr.central.Len() != 0:
-		t.Error("Win3: wr.central.Len() != 0")
-	case wr.left.Len() != 1:
-		t.Error("Win3: wr.left.Len() != 1")
-	case wr.right.Len() != 2:
-		t.Error("Win3: wr.right.Len() != 2")
+	if lens := columnLens(wr); !reflect.DeepEqual(lens, []int{1, 2}) {
+		t.Error("Win3: wrong columns", lens)
+	}
+	if wr.Sizing() != SizingEqual {
+		t.Error("Columns should have equal width", wr.Sizing())
 	}
 }
 
@@ -182,8 +297,8 @@ func TestWorkspaceRemoveCentral(t *testing.T) {
 	w1 := NewWindow(1, c, nil)
 	wr.Add(w1)
 	wr.Remove(w1)
-	if wr.central.Len() != 0 {
-		t.Error("wr.central.Len() != 0")
+	if len(wr.columns) != 0 || wr.focus != nil {
+		t.Error("Workspace isn't empty", columnLens(wr))
 	}
 }
 
@@ -196,13 +311,16 @@ func TestWorkspaceRemoveLastInColumn(t *testing.T) {
 	wr.Add(w1)
 	wr.Add(w2)
 	wr.Remove(w1)
-	if wr.central.Len() != 1 {
-		t.Error("wr.central.Len() != 0")
+	if lens := columnLens(wr); !reflect.DeepEqual(lens, []int{1}) {
+		t.Error("Empty column isn't removed", lens)
 	}
 	wr.Add(w1)
 	wr.Remove(w1)
-	if wr.central.Len() != 1 {
-		t.Error("wr.central.Len() != 0")
+	if lens := columnLens(wr); !reflect.DeepEqual(lens, []int{1}) {
+		t.Error("Empty column isn't removed", lens)
+	}
+	if wr.Sizing() != SizingFull {
+		t.Error("The only column should take full width", wr.Sizing())
 	}
 }
 
@@ -217,13 +335,934 @@ func TestWorkspaceRemove(t *testing.T) {
 	wr.Add(w2)
 	wr.Add(w3)
 	wr.Remove(w2)
-	if wr.central.Len() != 0 {
-		t.Error("wr.central.Len() != 0")
+	if lens := columnLens(wr); !reflect.DeepEqual(lens, []int{1, 1}) {
+		t.Error("Wrong columns", lens)
+	}
+}
+
+func TestWorkspaceReleaseHidden(t *testing.T) {
+	stubConfigure(t)
+
+	c := make(chan proto.Message)
+	wr := NewWorkspace(c, c, nil, 1, xutil.NewScreen(100, 60, 0, 0, 0))
+	w1 := NewWindow(1, c, nil)
+	w2 := NewWindow(2, c, nil)
+	wr.Add(w1)
+	wr.Add(w2)
+
+	// Workspace isn't activated, so windows must not take focus
+	wr.Release(w1)
+	if wr.focus != w2 {
+		t.Error("Focus should move to the remaining window", wr.focus)
+	}
+}
+
+func TestWorkspaceCloseUnfocused(t *testing.T) {
+	stubConfigure(t)
+
+	c := make(chan proto.Message)
+	wr := NewWorkspace(c, c, nil, 1, xutil.NewScreen(100, 60, 0, 0, 0))
+	w1 := NewWindow(1, c, nil)
+	w2 := NewWindow(2, c, nil)
+	w3 := NewWindow(3, c, nil)
+	wr.Add(w1)
+	wr.Add(w2)
+	wr.Add(w3)
+	wr.focus = w3
+
+	// Window closed with _NET_CLOSE_WINDOW isn't the focused one
+	wr.Release(w2)
+	if wr.focus != w3 {
+		t.Error("Focus should stay on the focused window", wr.focus)
+	}
+	if wr.FindWindow(w2.Id()) != nil || len(wr.Tiled()) != 2 {
+		t.Error("Closed window isn't removed")
+	}
+}
+
+func TestWorkspaceColumns(t *testing.T) {
+	stubConfigure(t)
+
+	c := make(chan proto.Message)
+	screen := xutil.NewScreen(120, 60, 0, 0, 0)
+	wr := NewWorkspace(c, c, nil, 1, screen)
+	w1 := NewWindow(1, c, nil)
+	w2 := NewWindow(2, c, nil)
+	w3 := NewWindow(3, c, nil)
+	w4 := NewWindow(4, c, nil)
+	wr.Add(w1)
+	wr.Add(w2)
+	wr.Add(w3)
+	wr.Add(w4)
+
+	wr.NewColumn(w3.Id())
+	if lens := columnLens(wr); !reflect.DeepEqual(lens, []int{1, 2, 1}) {
+		t.Fatal("New column isn't created", lens)
+	}
+	wr.Reshape()
+	if w1.width != 60 || w2.width != 30 || w3.width != 30 || w3.x != 90 {
+		t.Error("New column should take half of its column", w1.width, w2.width, w3.width, w3.x)
+	}
+
+	wr.MoveLeft(w3.Id())
+	if lens := columnLens(wr); !reflect.DeepEqual(lens, []int{1, 3}) {
+		t.Error("Window didn't move to the left column", lens)
+	}
+	wr.MoveLeft(w3.Id())
+	if lens := columnLens(wr); !reflect.DeepEqual(lens, []int{2, 2}) {
+		t.Error("Window didn't move to the left column", lens)
+	}
+
+	wr.NewColumn(w4.Id())
+	wr.MergeColumn(w4.Id())
+	if lens := columnLens(wr); !reflect.DeepEqual(lens, []int{2, 2}) {
+		t.Fatal("Column isn't merged", lens)
+	}
+	wr.Reshape()
+	if w1.width != 60 || w4.width != 60 {
+		t.Error("Merged column width should go to its neighbour", w1.width, w4.width)
+	}
+}
+
+func TestWorkspaceRestart(t *testing.T) {
+	stubConfigure(t)
+
+	c := make(chan proto.Message)
+	screen := xutil.NewScreen(120, 60, 0, 0, 0)
+	wr := NewWorkspace(c, c, nil, 1, screen)
+	for id := uint32(1); id <= 4; id++ {
+		wr.Add(NewWindow(id, c, nil))
+	}
+	wr.NewColumn(3)
+	wr.FindWindow(2).SetWeight(2)
+	w5 := NewWindow(5, c, nil)
+	w5.floating = true
+	wr.floating.Add(w5)
+	wr.focus = wr.FindWindow(3)
+	wr.Reshape()
+
+	data, err := json.Marshal(ipc.Tree{Workspaces: []ipc.WorkspaceState{wr.State()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tree ipc.Tree
+	if err := json.Unmarshal(data, &tree); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewWorkspace(c, c, nil, 1, screen)
+	added := restored.arrange(tree.Workspaces[0])
+	restored.Reshape()
+	if len(added) != 5 {
+		t.Error("Not all windows are restored", len(added))
+	}
+	if lens := columnLens(restored); !reflect.DeepEqual(lens, columnLens(wr)) {
+		t.Error("Columns aren't restored", lens, columnLens(wr))
+	}
+	for id := uint32(1); id <= 4; id++ {
+		before, after := wr.FindWindow(id), restored.FindWindow(id)
+		if after.x != before.x || after.width != before.width || after.height != before.height {
+			t.Error("Window isn't restored to its place", id, after.x, after.width, after.height)
+		}
+	}
+	if restored.focusId() != 3 {
+		t.Error("Focus isn't restored", restored.focusId())
+	}
+	if win := restored.FindWindow(5); win == nil || !win.IsFloating() {
+		t.Error("Floating window isn't restored")
+	}
+}
+
+func TestWorkspaceResize(t *testing.T) {
+	stubConfigure(t)
+
+	c := make(chan proto.Message)
+	screen := xutil.NewScreen(1200, 600, 0, 0, 0)
+	wr := NewWorkspace(c, c, nil, 1, screen)
+	w1 := NewWindow(1, c, nil)
+	w2 := NewWindow(2, c, nil)
+	w3 := NewWindow(3, c, nil)
+	wr.Add(w1)
+	wr.Add(w2)
+	wr.Add(w3)
+	wr.NewColumn(w3.Id())
+
+	wr.ResizeRight(w1.Id())
+	wr.Reshape()
+	if w1.width != 660 || w2.width != 240 || wr.Sizing() != SizingCustom {
+		t.Error("Column isn't resized by the step", w1.width, w2.width, wr.Sizing())
+	}
+
+	for i := 0; i < 10; i++ {
+		wr.ResizeLeft(w3.Id())
+	}
+	wr.Reshape()
+	if w2.width != 100 || w3.width != 440 {
+		t.Error("Column is narrower than the minimum", w2.width, w3.width)
+	}
+
+	wr.Equalize()
+	wr.Reshape()
+	if w1.width != 400 || w3.width != 400 || wr.Sizing() != SizingEqual {
+		t.Error("Columns aren't equalized", w1.width, w3.width, wr.Sizing())
+	}
+}
+
+func TestColumnGrow(t *testing.T) {
+	stubConfigure(t)
+
+	c := make(chan proto.Message)
+	column := NewColumn(xutil.NewScreen(100, 300, 0, 0, 0))
+	w1 := NewWindow(1, c, nil)
+	w2 := NewWindow(2, c, nil)
+	w3 := NewWindow(3, c, nil)
+	column.Add(w1)
+	column.Add(w2)
+	column.Add(w3)
+
+	column.Grow(0, 0.2, 0.1)
+	column.Reshape()
+	if w1.height != 160 || w2.height != 70 || w3.height != 70 || w2.y != 160 {
+		t.Error("Other windows should absorb the difference", w1.height, w2.height, w3.height)
+	}
+
+	column.Grow(0, 1, 0.1)
+	column.Reshape()
+	if w1.height != 240 || w2.height != 30 || w3.height != 30 {
+		t.Error("Window should leave minimum share to others", w1.height, w2.height, w3.height)
+	}
+
+	column.Grow(2, -1, 0.1)
+	column.Reshape()
+	if w3.height != 30 {
+		t.Error("Window should keep minimum share", w3.height)
+	}
+
+	column.Equalize()
+	column.Reshape()
+	if w1.height != 100 || w2.height != 100 || w3.height != 100 {
+		t.Error("Windows aren't equalized", w1.height, w2.height, w3.height)
+	}
+}
+
+func TestLayoutTiles(t *testing.T) {
+	area := Rect{0, 10, 100, 60}
+	master := masterTiles(3, area)
+	expected := []Rect{{0, 10, 55, 60}, {55, 10, 45, 30}, {55, 40, 45, 30}}
+	if !reflect.DeepEqual(master, expected) {
+		t.Error("Wrong master layout", master)
+	}
+
+	grid := gridTiles(5, area)
+	expected = []Rect{
+		{0, 10, 33, 30}, {33, 10, 33, 30}, {66, 10, 34, 30},
+		{0, 40, 50, 30}, {50, 40, 50, 30},
+	}
+	if !reflect.DeepEqual(grid, expected) {
+		t.Error("Wrong grid layout", grid)
+	}
+
+	spiral := spiralTiles(4, area)
+	expected = []Rect{{0, 10, 50, 60}, {50, 10, 50, 30}, {75, 40, 25, 30}, {50, 40, 25, 30}}
+	if !reflect.DeepEqual(spiral, expected) {
+		t.Error("Wrong spiral layout", spiral)
+	}
+
+	if tiles := monocleTiles(2, area); tiles[1] != area {
+		t.Error("Monocle window should take the whole area", tiles)
+	}
+}
+
+func TestColumnLayoutArea(t *testing.T) {
+	stubConfigure(t)
+
+	c := make(chan proto.Message)
+	screen := xutil.NewScreen(100, 60, 0, 0, 0)
+	wr := NewWorkspace(c, c, nil, 1, screen)
+	w1 := NewWindow(1, c, nil)
+	w2 := NewWindow(2, c, nil)
+	w3 := NewWindow(3, c, nil)
+	wr.Add(w1)
+	wr.Add(w2)
+	wr.Add(w3)
+	columnLayout{}.Arrange(wr, Rect{10, 20, 80, 30})
+	places := [][4]int{
+		{w1.x, w1.y, w1.width, w1.height},
+		{w2.x, w2.y, w2.width, w2.height},
+		{w3.x, w3.y, w3.width, w3.height},
+	}
+	expected := [][4]int{{10, 20, 40, 30}, {50, 20, 40, 15}, {50, 35, 40, 15}}
+	if !reflect.DeepEqual(places, expected) {
+		t.Error("Columns aren't placed within the area", places)
+	}
+}
+
+func TestWorkspaceLayouts(t *testing.T) {
+	stubConfigure(t)
+
+	c := make(chan proto.Message)
+	wr := NewWorkspace(c, c, nil, 1, xutil.NewScreen(100, 60, 0, 0, 0))
+	w1 := NewWindow(1, c, nil)
+	w2 := NewWindow(2, c, nil)
+	w3 := NewWindow(3, c, nil)
+	wr.Add(w1)
+	wr.Add(w2)
+	wr.Add(w3)
+
+	wr.SetLayout(nextLayout(wr.layout))
+	wr.Reshape()
+	if wr.Layout() != LayoutMaster || w1.width != 55 || w3.y != 30 {
+		t.Fatal("Windows aren't placed by the master layout", wr.Layout(), w1.width, w3.y)
+	}
+
+	wr.focus = w3
+	if focus := wr.FocusLeft(); focus != w1 {
+		t.Error("Focus should move to the master window", focus.Id())
+	}
+	if focus := wr.FocusUp(); focus != w2 {
+		t.Error("Focus should move within the stack", focus.Id())
+	}
+
+	wr.Move(proto.MoveLeft, w3.Id())
+	wr.Reshape()
+	if w3.width != 55 || w1.x != 55 {
+		t.Error("Window should be swapped with the master one", w3.width, w1.x)
+	}
+
+	wr.Remove(w2)
+	wr.Reshape()
+	if w3.width != 55 || w1.width != 45 || w1.height != 60 || len(wr.Tiled()) != 2 {
+		t.Error("Removed window should leave the master layout", w3.width, w1.width, w1.height)
+	}
+
+	for !wr.IsColumns() {
+		wr.SetLayout(nextLayout(wr.layout))
 	}
-	if wr.left.Len() != 1 {
-		t.Error("wr.left.Len() != 1")
+	wr.Reshape()
+	if wr.Layout() != LayoutColumns || wr.Sizing() != SizingEqual || w1.width != 50 {
+		t.Error("Columns should be restored after cycling", wr.Layout(), wr.Sizing(), w1.width)
 	}
-	if wr.right.Len() != 1 {
-		t.Error("wr.right.Len() != 1")
+}
+
+func TestWorkspaceAddToColumn(t *testing.T) {
+	c := make(chan proto.Message)
+	screen := xutil.NewScreen(8, 6, 0, 0, 0)
+	wr := NewWorkspace(c, c, nil, 1, screen)
+	w1 := NewWindow(1, c, nil)
+	w2 := NewWindow(2, c, nil)
+	w3 := NewWindow(3, c, nil)
+	w4 := NewWindow(4, c, nil)
+	wr.AddToColumn(w1, "right")
+	wr.AddToColumn(w2, "left")
+	if i, _ := wr.position(w2.Id()); i != 0 || len(wr.columns) != 2 {
+		t.Error("Window isn't placed to the left", columnLens(wr))
+	}
+	wr.AddToColumn(w3, "4")
+	wr.AddToColumn(w4, "2")
+	if lens := columnLens(wr); !reflect.DeepEqual(lens, []int{1, 2, 1}) {
+		t.Error("Windows aren't placed to the columns", lens)
+	}
+}
+
+func TestWorkspaceFocusNext(t *testing.T) {
+	c := make(chan proto.Message)
+	screen := xutil.NewScreen(8, 6, 0, 0, 0)
+	wr := NewWorkspace(c, c, nil, 1, screen)
+	w1 := NewWindow(1, c, nil)
+	w2 := NewWindow(2, c, nil)
+	w3 := NewWindow(3, c, nil)
+	wr.Add(w1)
+	wr.Add(w2)
+	wr.floating.Add(w3)
+	wr.focus = w2
+	if win := wr.FocusNext(); win != w3 {
+		t.Error("Focus didn't move to the floating window")
+	}
+	wr.focus = w3
+	if win := wr.FocusNext(); win != w1 {
+		t.Error("Focus didn't return to the tiled window")
+	}
+}
+
+func TestWorkspaceCenter(t *testing.T) {
+	stubConfigure(t)
+
+	c := make(chan proto.Message)
+	screen := xutil.NewScreen(8, 6, 0, 0, 0)
+	wr := NewWorkspace(c, c, nil, 1, screen)
+	parent := NewWindow(1, c, nil)
+	parent.width, parent.height = 4, 6
+	win := NewWindow(2, c, nil)
+	win.width, win.height = 2, 2
+	wr.Center(win, parent)
+	if win.x != 1 || win.y != 2 {
+		t.Error("Window isn't centered over parent", win.x, win.y)
+	}
+
+	win.width, win.height = 10, 2
+	wr.Center(win, nil)
+	if win.x != 0 || win.width != 8 {
+		t.Error("Window doesn't fit the screen", win.x, win.width)
+	}
+}
+
+func TestLayoutFloatingRoundTrip(t *testing.T) {
+	c := make(chan proto.Message)
+	screen := xutil.NewScreen(8, 6, 0, 0, 0)
+	wr := NewWorkspace(c, c, nil, 1, screen)
+	wr.Add(NewWindow(1, c, nil))
+	wr.floating.Add(NewWindow(2, c, nil))
+
+	classes := map[uint32]string{1: "XTerm", 2: "Gimp"}
+	tree := ipc.Tree{Workspaces: []ipc.WorkspaceState{wr.State()}}
+	layout := describeLayout(tree, func(wid uint32) (session.Window, bool) {
+		return session.Window{Class: classes[wid]}, true
+	})
+
+	ids := map[string]uint32{"XTerm": 1, "Gimp": 2}
+	restored := NewWorkspace(c, c, nil, 1, screen)
+	for _, slot := range layout.Slots() {
+		win := NewWindow(ids[slot.Class], c, nil)
+		if options := slotOptions(slot.Position); options.Floating {
+			restored.floating.Add(win)
+		} else {
+			restored.AddToColumn(win, options.Column)
+		}
+	}
+	if restored.floating.IndexById(2) < 0 {
+		t.Error("Floating window is restored tiled", layout.Slots())
+	}
+	if lens := columnLens(restored); !reflect.DeepEqual(lens, []int{1}) {
+		t.Error("Tiled window isn't restored to its column", lens)
+	}
+}
+
+func TestParseCmdline(t *testing.T) {
+	args := parseCmdline([]byte("xterm\x00-title\x00a b\x00"))
+	if !reflect.DeepEqual(args, []string{"xterm", "-title", "a b"}) {
+		t.Error("Wrong arguments", args)
+	}
+	if args := parseCmdline(nil); args != nil {
+		t.Error("Empty command line should give no arguments", args)
+	}
+}
+
+func TestSetAtom(t *testing.T) {
+	atoms := []xproto.Atom{3, 5}
+	if result := xutil.SetAtom(atoms, 7, true); !reflect.DeepEqual(result, []xproto.Atom{3, 5, 7}) {
+		t.Error("Atom isn't added", result)
+	}
+	if result := xutil.SetAtom([]xproto.Atom{3, 7, 5}, 7, false); !reflect.DeepEqual(result, []xproto.Atom{3, 5}) {
+		t.Error("Other states should be kept", result)
+	}
+	if result := xutil.SetAtom([]xproto.Atom{7, 3}, 7, true); !reflect.DeepEqual(result, []xproto.Atom{3, 7}) {
+		t.Error("Atom shouldn't be repeated", result)
+	}
+}
+
+func TestParseAction(t *testing.T) {
+	action, err := ParseAction("move-to-workspace 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if action.Workspace() != 3 {
+		t.Error("Wrong workspace", action.Workspace())
+	}
+
+	invalid := []string{
+		"", "teleport", "focus", "focus north", "workspace 0",
+		"workspace 10", "close now", "spawn", "save-layout",
+		"restore-layout dev work", "layout", "layout tabbed",
+	}
+	for _, s := range invalid {
+		if _, err := ParseAction(s); err == nil {
+			t.Errorf("Invalid action %q accepted", s)
+		}
+	}
+}
+
+func TestParseActionLayout(t *testing.T) {
+	c := make(chan proto.Message)
+	screen := xutil.NewScreen(8, 6, 0, 0, 0)
+	wr := NewWorkspace(c, c, nil, 1, screen)
+	for _, layout := range layouts {
+		wr.layout = layout
+		action, err := ParseAction("layout " + wr.Layout())
+		if err != nil {
+			t.Errorf("Reported layout %q isn't accepted: %v", wr.Layout(), err)
+		} else if !reflect.DeepEqual(action.Args, []string{layout.Name()}) {
+			t.Error("Wrong layout argument", action.Args)
+		}
+	}
+
+	invalid := []string{
+		"layout " + SizingFull, "layout " + SizingEqual,
+		"layout " + SizingCustom, "layout grid spiral", "layout Grid",
+	}
+	for _, s := range invalid {
+		if _, err := ParseAction(s); err == nil {
+			t.Errorf("Invalid action %q accepted", s)
+		}
+	}
+}
+
+func TestDefaultBindingsAreValid(t *testing.T) {
+	if len(config.Bindings()) == 0 {
+		t.Fatal("No default bindings")
+	}
+	for _, binding := range config.Bindings() {
+		if _, err := ParseAction(binding.Action); err != nil {
+			t.Error(binding.Keys, err)
+		}
+	}
+}
+
+func TestWorkspaceStateJSON(t *testing.T) {
+	stubConfigure(t)
+
+	c := make(chan proto.Message)
+	screen := xutil.NewScreen(80, 60, 0, 0, 0)
+	wr := NewWorkspace(c, c, nil, 1, screen)
+	w1 := NewWindow(1, c, nil)
+	w2 := NewWindow(2, c, nil)
+	w3 := NewWindow(3, c, nil)
+	wr.Add(w1)
+	wr.Add(w2)
+	w3.floating = true
+	wr.floating.Add(w3)
+	wr.focus = w2
+	wr.Reshape()
+
+	data, err := json.Marshal(ipc.Tree{Current: 1, Workspaces: []ipc.WorkspaceState{wr.State()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tree ipc.Tree
+	if err := json.Unmarshal(data, &tree); err != nil {
+		t.Fatal(err)
+	}
+
+	ws := tree.Workspaces[0]
+	if ws.ID != 1 || ws.Layout != LayoutColumns || ws.Sizing != SizingEqual || ws.Focus != 2 {
+		t.Error("Wrong workspace state", ws.ID, ws.Layout, ws.Sizing, ws.Focus)
+	}
+	var positions []string
+	for _, column := range ws.Columns {
+		positions = append(positions, column.Position)
+	}
+	if !reflect.DeepEqual(positions, []string{"1", "2", ipc.PositionFloating}) {
+		t.Fatal("Wrong column positions", positions)
+	}
+	if col := ws.Columns[1]; col.X != 40 || col.Width != 40 || !col.Windows[0].Focused {
+		t.Error("Wrong state of the focused column", col)
+	}
+	if win := ws.Columns[2].Windows[0]; win.ID != 3 || !win.Floating || win.Focused {
+		t.Error("Wrong state of the floating window", win)
+	}
+
+	grid, _ := LayoutByName(LayoutGrid)
+	wr.SetLayout(grid)
+	if state := wr.State(); state.Layout != LayoutGrid || state.Sizing != "" {
+		t.Error("Sizing should be reported for columns only", state.Layout, state.Sizing)
+	}
+}
+
+func TestWorkspaceManagerTree(t *testing.T) {
+	manager := NewWorkspaceManager(xutil.NewMonitorsInfo(
+		xutil.NewScreen(1920, 1080, 0, 0, 0),
+		xutil.NewScreen(1280, 1024, 1920, 0, 0),
+	))
+	tree := manager.Tree(nil)
+	if tree.Current != 1 || !reflect.DeepEqual(tree.Visible, []uint32{1, 9}) {
+		t.Error("Wrong current or visible workspaces", tree.Current, tree.Visible)
+	}
+	if len(tree.Workspaces) != MaxWorkspaces {
+		t.Fatal("Every workspace should be described", len(tree.Workspaces))
+	}
+	for i, ws := range tree.Workspaces {
+		if ws.ID != uint32(i+1) || ws.Layout != LayoutColumns || ws.Focus != 0 {
+			t.Error("Wrong state of empty workspace", ws)
+		}
+	}
+}
+
+func TestControlSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wmwm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "wmwm.sock")
+	os.Setenv("WMWM_SOCKET", path)
+	defer os.Unsetenv("WMWM_SOCKET")
+
+	requests := make(chan ControlRequest)
+	listener, err := ListenControl(path, requests)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	go func() {
+		request := <-requests
+		if request.Command != "focus left" {
+			request.Reply <- ipc.Response{Error: "unexpected " + request.Command}
+			return
+		}
+		request.Reply <- ipc.Response{Ok: true}
+	}()
+
+	if _, err := ipc.Send("focus left"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestControlQuitReply(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wmwm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "wmwm.sock")
+	os.Setenv("WMWM_SOCKET", path)
+	defer os.Unsetenv("WMWM_SOCKET")
+
+	requests := make(chan ControlRequest)
+	listener, err := ListenControl(path, requests)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	handled := make(chan bool)
+	go func() {
+		request := <-requests
+		err := handleControl(request, nil, xproto.ScreenInfo{}, nil, nil)
+		select {
+		case <-request.Done:
+			handled <- err == errQuit
+		default:
+			handled <- false
+		}
+	}()
+
+	response, err := ipc.Send("quit")
+	if err != nil || !response.Ok {
+		t.Error("Quit isn't acknowledged", response, err)
+	}
+	if !<-handled {
+		t.Error("Quit is returned before the reply is written")
+	}
+}
+
+func TestEventSubscription(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wmwm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "wmwm.sock")
+	os.Setenv("WMWM_SOCKET", path)
+	defer os.Unsetenv("WMWM_SOCKET")
+
+	listener, err := ListenControl(path, make(chan ControlRequest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	if _, err := ipc.Subscribe("bogus"); err == nil {
+		t.Error("Subscribed to unknown event")
+	}
+
+	sub, err := ipc.Subscribe(ipc.EventFocus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	eventHub.Publish(ipc.Event{Event: ipc.EventWorkspace, Workspace: 2})
+	eventHub.Publish(ipc.Event{Event: ipc.EventFocus, Workspace: 2, Window: 7})
+	event, err := sub.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Event != ipc.EventFocus || event.Window != 7 {
+		t.Error("Unexpected event", event)
+	}
+}
+
+func TestClientListMove(t *testing.T) {
+	list := NewClientList()
+	list.Add(7, 1)
+	// Window is attached to the second workspace before
+	// the first one reports its removal
+	list.Add(7, 2)
+	list.Remove(7, 1)
+	if id, ok := list.Owner(7); !ok || id != 2 {
+		t.Error("Moved window is lost", id, ok)
+	}
+	list.Remove(7, 2)
+	if _, ok := list.Owner(7); ok {
+		t.Error("Removed window is still managed")
+	}
+}
+
+func TestClientListStacking(t *testing.T) {
+	list := NewClientList()
+	var order, stacking []uint32
+	var active uint32
+	list.OnChange(func(o, s []uint32, a uint32) {
+		order, stacking, active = o, s, a
+	})
+	list.Add(1, 1)
+	list.Add(2, 1)
+	list.Add(3, 2)
+	list.Raise(1)
+	list.SetActive(2)
+	if len(order) != 3 || order[0] != 1 || stacking[2] != 1 || active != 2 {
+		t.Error("Wrong lists", order, stacking, active)
+	}
+	list.Remove(2, 1)
+	if len(order) != 2 || active != 0 {
+		t.Error("Removed window is listed", order, active)
+	}
+}
+
+func TestClientListHandlerUnlocked(t *testing.T) {
+	list := NewClientList()
+	var owner uint32
+	list.OnChange(func(o, s []uint32, a uint32) {
+		// Reading the list from the handler would deadlock
+		// if the handler were called under the lock
+		if len(o) > 0 {
+			owner, _ = list.Owner(o[0])
+		}
+	})
+	list.Add(4, 3)
+	if owner != 3 {
+		t.Error("Handler can't read the list", owner)
+	}
+}
+
+func TestMoveResizeRequest(t *testing.T) {
+	// Gravity is static, x and height are set
+	e := moveResizeRequest(5, []uint32{10 | 1<<8 | 1<<11, 20, 30, 40, 50})
+	if e.ValueMask != xproto.ConfigWindowX|xproto.ConfigWindowHeight {
+		t.Error("Wrong value mask", e.ValueMask)
+	}
+	if e.Window != 5 || e.X != 20 || e.Height != 50 {
+		t.Error("Wrong request", e)
+	}
+}
+
+func TestChangesState(t *testing.T) {
+	if changesState(proto.StateAdd, true) || changesState(proto.StateRemove, false) {
+		t.Error("State is changed to the same one")
+	}
+	if !changesState(proto.StateToggle, true) || !changesState(proto.StateAdd, false) {
+		t.Error("State isn't changed")
+	}
+}
+
+func TestMonitorsAt(t *testing.T) {
+	monitors := xutil.NewMonitorsInfo(
+		xutil.NewScreen(1920, 1080, 0, 0, 0),
+		xutil.NewScreen(1280, 1024, 1920, 0, 0),
+		xutil.NewScreen(1280, 1024, 3200, 0, 0),
+	)
+	cases := []struct {
+		x, y    int
+		monitor int
+	}{
+		{10, 10, 0}, {1919, 1079, 0}, {1920, 0, 1}, {3300, 500, 2}, {5000, 0, 0},
+	}
+	for _, c := range cases {
+		if m := monitors.At(c.x, c.y); m != c.monitor {
+			t.Errorf("Point (%d, %d) is on monitor %d, expected %d", c.x, c.y, m, c.monitor)
+		}
+	}
+}
+
+func TestWorkspaceManagerMonitors(t *testing.T) {
+	manager := NewWorkspaceManager(xutil.NewMonitorsInfo(
+		xutil.NewScreen(1920, 1080, 0, 0, 0),
+		xutil.NewScreen(1280, 1024, 1920, 0, 0),
+		xutil.NewScreen(1280, 1024, 3200, 0, 0),
+	))
+	if !reflect.DeepEqual(manager.VisibleAll(), []uint32{1, 8, 9}) {
+		t.Fatal("Wrong initial workspaces", manager.VisibleAll())
+	}
+
+	manager.SetCurr(9)
+	if manager.Monitor() != 2 || manager.Curr() != 9 {
+		t.Error("Visible workspace should focus its monitor", manager.Monitor())
+	}
+	manager.SetCurr(3)
+	if !reflect.DeepEqual(manager.VisibleAll(), []uint32{1, 8, 3}) {
+		t.Error("Hidden workspace should replace the focused one", manager.VisibleAll())
+	}
+	if manager.Visible(9) || !manager.Visible(3) {
+		t.Error("Wrong visibility of workspaces")
+	}
+	if screen := manager.Screen(3); screen.XOffset() != 3200 {
+		t.Error("Workspace is placed on the wrong screen", screen.XOffset())
+	}
+
+	manager.SetVisible([]uint32{8, 1})
+	if !reflect.DeepEqual(manager.VisibleAll(), []uint32{8, 1, 3}) {
+		t.Error("Workspaces should swap monitors", manager.VisibleAll())
+	}
+}
+
+func TestAdoptedWorkspace(t *testing.T) {
+	manager := NewWorkspaceManager(xutil.NewMonitorsInfo(
+		xutil.NewScreen(1920, 1080, 0, 0, 0),
+	))
+	manager.SetCurr(3)
+	cases := []struct {
+		desktop  uint32
+		ok       bool
+		expected uint32
+	}{
+		{0, true, 1},
+		{4, true, 5},
+		{manager.Workspaces() - 1, true, manager.Workspaces()},
+		{manager.Workspaces(), true, 3},
+		{0xFFFFFFFF, true, 3},
+		{1, false, 3},
+	}
+	for _, c := range cases {
+		if id := adoptedWorkspace(c.desktop, c.ok, manager); id != c.expected {
+			t.Error("Wrong workspace for desktop", c.desktop, c.ok, id)
+		}
+	}
+
+	attrs := []struct {
+		attrs    xproto.GetWindowAttributesReply
+		expected bool
+	}{
+		{xproto.GetWindowAttributesReply{MapState: xproto.MapStateViewable}, true},
+		{xproto.GetWindowAttributesReply{MapState: xproto.MapStateUnmapped}, false},
+		{xproto.GetWindowAttributesReply{MapState: xproto.MapStateUnviewable}, false},
+		{xproto.GetWindowAttributesReply{
+			MapState: xproto.MapStateViewable, OverrideRedirect: true,
+		}, false},
+	}
+	for _, a := range attrs {
+		if xutil.Viewable(&a.attrs) != a.expected {
+			t.Error("Wrong adoption of window", a.attrs.MapState, a.attrs.OverrideRedirect)
+		}
+	}
+}
+
+func TestWorkspaceManagerSetMonitors(t *testing.T) {
+	primary := xutil.NewScreen(1920, 1080, 0, 0, 0)
+	external := xutil.NewScreen(1280, 1024, 1920, 0, 0)
+	manager := NewWorkspaceManager(xutil.NewMonitorsInfo(primary, external, external))
+	manager.SetCurr(9)
+
+	hidden, shown := manager.SetMonitors(xutil.NewMonitorsInfo(primary))
+	if !reflect.DeepEqual(hidden, []uint32{8, 9}) || len(shown) > 0 {
+		t.Error("Workspaces of removed monitors should be hidden", hidden, shown)
+	}
+	if manager.Monitor() != 0 || manager.Curr() != 1 {
+		t.Error("Focus should move to the primary monitor", manager.Curr())
+	}
+	if screen := manager.Screen(9); screen.XOffset() != 0 {
+		t.Error("Hidden workspace should be placed on remaining monitor")
+	}
+
+	hidden, shown = manager.SetMonitors(xutil.NewMonitorsInfo(primary, external))
+	if len(hidden) > 0 || !reflect.DeepEqual(shown, []uint32{9}) {
+		t.Error("Added monitor should show the last workspace", hidden, shown)
+	}
+	if screen := manager.Screen(9); screen.XOffset() != 1920 {
+		t.Error("Workspace is placed on the wrong screen", screen.XOffset())
+	}
+}
+
+func TestParseStrut(t *testing.T) {
+	value := make([]byte, 12*4)
+	for i, v := range []uint32{0, 0, 24, 0, 0, 0, 0, 0, 0, 1919, 0, 0} {
+		xgb.Put32(value[i*4:], v)
+	}
+	strut, err := xutil.ParseStrut(value)
+	if err != nil || strut.Top != 24 || strut.TopEndX != 1919 {
+		t.Error("Wrong partial strut", strut, err)
+	}
+
+	strut, err = xutil.ParseStrut(value[:4*4])
+	if err != nil || strut.Top != 24 || strut.TopEndX < 1919 {
+		t.Error("Legacy strut should reserve the whole edge", strut, err)
+	}
+
+	if _, err := xutil.ParseStrut(value[:8]); err == nil {
+		t.Error("Short strut should be rejected")
+	}
+}
+
+func TestMonitorsWithStruts(t *testing.T) {
+	monitors := xutil.NewMonitorsInfo(
+		xutil.NewScreen(1920, 1080, 0, 30, 0),
+		xutil.NewScreen(1280, 1024, 1920, 0, 0),
+	)
+	if primary := monitors.WithStruts(nil).Primary(); primary.PaddingTop() != 30 {
+		t.Error("Configured paddings should be used without struts")
+	}
+	// Dock without strut reserves no space
+	if primary := monitors.WithStruts([]xutil.Strut{{}}).Primary(); primary.PaddingTop() != 30 {
+		t.Error("Configured paddings should be used if struts are empty")
+	}
+
+	monitors = monitors.WithStruts([]xutil.Strut{
+		{Top: 24, TopStartX: 0, TopEndX: 1919},
+		{Bottom: 80, BottomStartX: 1920, BottomEndX: 3199},
+		{Right: 40, RightStartY: 0, RightEndY: 1023},
+	})
+	primary, external := monitors.Screen(0), monitors.Screen(1)
+	if primary.PaddingTop() != 24 || primary.PaddingBottom() != 0 || primary.PaddingRight() != 0 {
+		t.Error("Wrong paddings of primary monitor",
+			primary.PaddingTop(), primary.PaddingBottom(), primary.PaddingRight())
+	}
+	// Root window is 1080 pixels high, so bottom strut covers
+	// 80 - (1080 - 1024) pixels of the external monitor
+	if external.PaddingTop() != 0 || external.PaddingBottom() != 24 || external.PaddingRight() != 40 {
+		t.Error("Wrong paddings of external monitor",
+			external.PaddingTop(), external.PaddingBottom(), external.PaddingRight())
+	}
+	if x, y, w, h := external.WorkArea(); x != 1920 || y != 0 || w != 1240 || h != 1000 {
+		t.Error("Wrong work area", x, y, w, h)
+	}
+}
+
+func TestMonitorsWithStrutsPerEdge(t *testing.T) {
+	monitors := xutil.NewMonitorsInfo(
+		xutil.NewScreen(1920, 1080, 0, 30, 20),
+		xutil.NewScreen(1280, 1024, 1920, 0, 0),
+	)
+	// The only dock is placed at the top of the external monitor
+	monitors = monitors.WithStruts([]xutil.Strut{
+		{Top: 24, TopStartX: 1920, TopEndX: 3199},
+	})
+	primary, external := monitors.Screen(0), monitors.Screen(1)
+	if primary.PaddingTop() != 30 || primary.PaddingBottom() != 20 {
+		t.Error("Configured paddings should be kept on monitor without docks",
+			primary.PaddingTop(), primary.PaddingBottom())
+	}
+	if external.PaddingTop() != 24 || external.PaddingBottom() != 0 {
+		t.Error("Dock should reserve space on its monitor",
+			external.PaddingTop(), external.PaddingBottom())
+	}
+
+	monitors = monitors.WithStruts([]xutil.Strut{
+		{Bottom: 40, BottomStartX: 0, BottomEndX: 1919},
+	})
+	if primary := monitors.Primary(); primary.PaddingTop() != 30 || primary.PaddingBottom() != 40 {
+		t.Error("Configured padding should be kept on edge without docks",
+			primary.PaddingTop(), primary.PaddingBottom())
 	}
 }
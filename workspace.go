@@ -3,35 +3,39 @@ package main
 
 import (
 	"fmt"
+	"math"
+	"strconv"
 	"sync"
 
 	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/xproto"
 	"github.com/Zamony/wmwm/config"
+	"github.com/Zamony/wmwm/ipc"
 	"github.com/Zamony/wmwm/logging"
 	"github.com/Zamony/wmwm/proto"
 	"github.com/Zamony/wmwm/xutil"
 )
 
-// Layouts affect width of the columns
+// Sizings describe width of the columns of the column layout to the clients
 const (
-	LayoutFull = iota
-	LayoutEqual
-	LayoutLeftWide
+	SizingFull   = "full"
+	SizingEqual  = "equal"
+	SizingCustom = "custom"
 )
 
 const (
+	// Name is the window manager name reported to clients
+	Name = "wmwm"
 	// MaxWorkspaces sets the number of workspaces available
 	MaxWorkspaces = 9
 	// DefaultWorkspace sets default active workspace
 	DefaultWorkspace = 1
-	// DefaultLayout sets default column layout
-	DefaultLayout = LayoutEqual
+	// MinHeightShare is a minimum share of the column height
+	// left to the window when another window of the column grows
+	MinHeightShare = 0.1
 )
 
-var (
-	unmapLock  ReattachLock
-	attachLock ReattachLock
-)
+var unmapLock ReattachLock
 
 // ReattachLock represents lock that can be harmlessly unlocked
 // even if there are not any locked goroutines
@@ -57,14 +61,19 @@ func (m *ReattachLock) Unlock() {
 
 // Workspace represents a group of related windows
 type Workspace struct {
-	left    *Column
-	right   *Column
-	central *Column
+	// columns hold tiled windows, columns are placed from left to right
+	columns []*Column
+	// floating holds windows which aren't tiled
+	floating *Column
+	// layout places tiled windows
+	layout Layout
+	// visible is set while the workspace is shown on a monitor
+	visible bool
+	screen  xutil.Screen
 	input   chan proto.Message
 	next    chan proto.Message
 	headc   chan proto.Message
 	id      uint32
-	layout  int
 	focus   *Window
 	conn    *xgb.Conn
 }
@@ -72,16 +81,15 @@ type Workspace struct {
 // NewWorkspace creates instance of Workspace
 func NewWorkspace(headc, input, next chan proto.Message, id uint32, screen xutil.Screen) *Workspace {
 	return &Workspace{
-		left:    NewColumn(screen),
-		right:   NewColumn(screen),
-		central: NewColumn(screen),
-		input:   input,
-		next:    next,
-		headc:   headc,
-		id:      id,
-		layout:  LayoutFull,
-		focus:   nil,
-		conn:    nil,
+		floating: NewColumn(screen),
+		layout:   layouts[0],
+		screen:   screen,
+		input:    input,
+		next:     next,
+		headc:    headc,
+		id:       id,
+		focus:    nil,
+		conn:     nil,
 	}
 }
 
@@ -117,67 +125,107 @@ func (workspace *Workspace) Run() {
 }
 
 func (workspace *Workspace) handleMsg(msg proto.Message) {
+	if msg.Type == proto.Query {
+		if reply, ok := msg.Data.(chan ipc.WorkspaceState); ok {
+			reply <- workspace.State()
+		}
+		return
+	}
+
 	workspace.LogStatus()
+	focus, layout, sizing := workspace.focusId(), workspace.Layout(), workspace.Sizing()
 	switch msg.Type {
 	case proto.Reattach:
 		win := NewWindow(workspace.id, workspace.headc, msg.XConn)
-		go func() { win.SendDetach(msg.From) }()
+		go func() { win.SendDetachWith(msg.From, msg.Data) }()
 	case proto.Attach:
 		win := NewWindow(msg.From, workspace.headc, msg.XConn)
-		if win.IsDock() {
-			win.Map()
-			break
-		}
+		win.LoadHints()
 		if workspace.FindWindow(msg.From) == nil {
-			workspace.Add(win)
+			options, _ := msg.Data.(proto.AttachOptions)
+			if options.Floating {
+				workspace.AddFloating(win, options.Parent)
+				if !options.NoFocus {
+					workspace.focus.Defocus()
+					workspace.focus = win
+				}
+			} else {
+				workspace.AddToColumn(win, options.Column)
+			}
+			if options.Fullscreen {
+				win.SetFullscreen(true)
+			}
 			workspace.Reshape()
-			if workspace.id == MaxWorkspaces {
-				workspace.Activate()
+			workspace.Restack()
+			workspace.publish(ipc.EventAttach, win.Id())
+			if options.Hide {
+				win.Hide()
+			}
+			if options.Show {
+				win.Map()
+				win.Raise()
 			}
 		}
 	case proto.Detach:
-		if workspace.focus != nil {
-			win := workspace.focus
+		win := workspace.focus
+		options, _ := msg.Data.(proto.DetachOptions)
+		if options.Window != 0 {
+			win = workspace.FindWindow(options.Window)
+		}
+		attach := proto.AttachOptions{Show: options.Show}
+		if win != nil {
+			// Window attached to another workspace is created anew
+			attach.Fullscreen = win.IsFullscreen()
+		}
+		if win != nil && !xutil.IsViewable(win.Id(), msg.XConn) {
+			// Hidden window won't be unmapped, so it is released at once
+			workspace.Release(win)
+			go win.SendAttachWith(msg.From, attach)
+		} else if win != nil {
 			win.Unmap()
 			go func() {
 				unmapLock.Lock()
-				win.SendAttach(msg.From)
-				attachLock.Unlock()
+				win.SendAttachWith(msg.From, attach)
 			}()
 		}
 	case proto.Remove:
 		win := workspace.FindWindow(msg.From)
-		if win != nil && workspace.focus != nil {
+		if win != nil {
 			if !win.IsRemovalAllowed() {
 				win.AllowRemoval()
 				break
 			}
-			if win.Id() == workspace.focus.Id() {
+			if win.Id() == workspace.focusId() {
 				win.Defocus()
 				workspace.Refocus()
 			}
 			workspace.Remove(win)
 			workspace.Reshape()
 			workspace.Focus()
+			workspace.publish(ipc.EventRemove, win.Id())
 			unmapLock.Unlock()
 		}
-
+	case proto.Release:
+		if win := workspace.FindWindow(msg.From); win != nil {
+			workspace.Release(win)
+		}
 	case proto.Close:
 		win := workspace.focus
+		if wid, ok := msg.Data.(uint32); ok {
+			win = workspace.FindWindow(wid)
+		}
 		if win == nil {
 			break
 		}
 		if win.CouldBeDestroyed() {
-			win.Defocus()
-			workspace.Refocus()
-			workspace.Remove(win)
-			workspace.Reshape()
+			// Focus moves away only if the closed window has it
+			workspace.Release(win)
 			win.Destroy()
 		} else {
 			win.Close()
 		}
 	case proto.FocusHere:
-		if workspace.focus.Id() != msg.From {
+		if workspace.focusId() != msg.From {
 			win := workspace.FindWindow(msg.From)
 			if win != nil {
 				workspace.focus.Defocus()
@@ -201,235 +249,785 @@ func (workspace *Workspace) handleMsg(msg proto.Message) {
 		workspace.focus.Defocus()
 		workspace.focus = workspace.FocusDown()
 		workspace.Focus()
-	case proto.Maximize:
-		if workspace.central.HasPadding() {
-			workspace.central.RemovePadding()
-		} else {
-			workspace.central.AddPadding()
+	case proto.Fullscreen:
+		win := workspace.FindWindow(msg.From)
+		if win == nil {
+			win = workspace.focus
+		}
+		if win == nil {
+			break
+		}
+		action, ok := msg.Data.(int)
+		if !ok {
+			action = proto.StateToggle
+		}
+		if changesState(action, win.IsFullscreen()) {
+			win.SetFullscreen(!win.IsFullscreen())
+			workspace.Reshape()
+			workspace.Restack()
+			workspace.Focus()
+		}
+	case proto.FocusNext:
+		if workspace.focus != nil {
+			workspace.focus.Defocus()
+			workspace.focus = workspace.FocusNext()
+			workspace.Focus()
+		}
+	case proto.ToggleFloating:
+		if workspace.focus != nil {
+			workspace.ToggleFloating(workspace.focus)
+			workspace.Reshape()
+			workspace.Restack()
+			workspace.Focus()
 		}
-		workspace.Activate()
 	case proto.Activate:
 		workspace.Reshape()
 		workspace.Activate()
 		workspace.Focus()
+		if workspace.focus == nil {
+			clients.SetActive(0)
+		}
 		xutil.SetCurrentDesktop(workspace.id, msg.XConn)
 	case proto.Deactivate:
-		if workspace.id != MaxWorkspaces {
-			workspace.Deactivate()
+		workspace.Deactivate()
+	case proto.Reload:
+		if screen, ok := msg.Data.(xutil.Screen); ok {
+			workspace.SetScreen(screen)
+		}
+		workspace.Reshape()
+		if workspace.focus != nil && !workspace.IsSingle() {
+			workspace.focus.SetBorder()
 		}
 	case proto.ResizeLeft:
-		workspace.ResizeLeft(msg.From)
-		workspace.Focus()
+		if workspace.focus != nil && workspace.IsColumns() {
+			workspace.ResizeLeft(workspace.focus.Id())
+			workspace.Reshape()
+			workspace.Focus()
+		}
 	case proto.ResizeRight:
-		workspace.ResizeRight(msg.From)
-		workspace.Focus()
-	case proto.MoveUp:
-		workspace.MoveUp(workspace.focus.Id())
-		workspace.Reshape()
-		workspace.Focus()
-	case proto.MoveDown:
-		workspace.MoveDown(workspace.focus.Id())
+		if workspace.focus != nil && workspace.IsColumns() {
+			workspace.ResizeRight(workspace.focus.Id())
+			workspace.Reshape()
+			workspace.Focus()
+		}
+	case proto.ResizeUp, proto.ResizeDown:
+		if workspace.focus != nil && workspace.IsColumns() {
+			delta := config.ResizeStep()
+			if msg.Type == proto.ResizeDown {
+				delta = -delta
+			}
+			workspace.ResizeHeight(workspace.focus.Id(), delta)
+			workspace.Reshape()
+			workspace.Focus()
+		}
+	case proto.Equalize:
+		workspace.Equalize()
 		workspace.Reshape()
 		workspace.Focus()
-	case proto.MoveLeft:
-		workspace.MoveLeft(workspace.focus.Id())
+	case proto.NewColumn:
+		if workspace.focus != nil && workspace.IsColumns() {
+			workspace.NewColumn(workspace.focus.Id())
+			workspace.Reshape()
+			workspace.Focus()
+		}
+	case proto.MergeColumn:
+		if workspace.focus != nil && workspace.IsColumns() {
+			workspace.MergeColumn(workspace.focus.Id())
+			workspace.Reshape()
+			workspace.Focus()
+		}
+	case proto.Layout:
+		name, _ := msg.Data.(string)
+		layout, ok := LayoutByName(name)
+		if !ok {
+			layout = nextLayout(workspace.layout)
+		}
+		workspace.SetLayout(layout)
 		workspace.Reshape()
+		workspace.Restack()
 		workspace.Focus()
-	case proto.MoveRight:
-		workspace.MoveRight(workspace.focus.Id())
+	case proto.Configure:
+		win := workspace.FindWindow(msg.From)
+		request, ok := msg.Data.(xproto.ConfigureRequestEvent)
+		if win == nil || !ok {
+			break
+		}
+		if win.IsFloating() {
+			win.Configure(request)
+		} else {
+			win.NotifyGeometry()
+		}
+	case proto.Hints:
+		if win := workspace.FindWindow(msg.From); win != nil {
+			win.LoadHints()
+			workspace.Reshape()
+		}
+	case proto.Title:
+		workspace.publish(ipc.EventTitle, msg.From)
+	case proto.Restore:
+		if arrangement, ok := msg.Data.(Arrangement); ok {
+			workspace.Restore(arrangement)
+			workspace.Reshape()
+		}
+	case proto.MoveUp, proto.MoveDown, proto.MoveLeft, proto.MoveRight:
+		if workspace.focus == nil {
+			break
+		}
+		workspace.Move(msg.Type, workspace.focus.Id())
 		workspace.Reshape()
 		workspace.Focus()
 	default:
 		return
 	}
 
+	if layout != workspace.Layout() || sizing != workspace.Sizing() {
+		workspace.publish(ipc.EventLayout, 0)
+	}
+	if focus != workspace.focusId() {
+		workspace.publish(ipc.EventFocus, workspace.focusId())
+	}
 	workspace.ChangeName()
 	workspace.LogStatus()
 }
 
-// MoveLeft moves window to the left column
+// changesState checks whether the state action
+// changes the current state of the window
+func changesState(action int, enabled bool) bool {
+	switch action {
+	case proto.StateAdd:
+		return !enabled
+	case proto.StateRemove:
+		return enabled
+	}
+	return true
+}
+
+// focusId returns identifier of the focused window or zero
+func (workspace *Workspace) focusId() uint32 {
+	if workspace.focus == nil {
+		return 0
+	}
+	return workspace.focus.Id()
+}
+
+// publish notifies subscribers about changes in the workspace
+// and keeps the list of managed windows up to date
+func (workspace *Workspace) publish(name string, wid uint32) {
+	event := ipc.Event{Event: name, Workspace: workspace.id, Window: wid}
+	switch name {
+	case ipc.EventLayout:
+		event.Layout, event.Sizing = workspace.Layout(), workspace.Sizing()
+	case ipc.EventAttach:
+		clients.Add(wid, workspace.id)
+		xutil.SetWMDesktop(wid, workspace.id, workspace.conn)
+		event.Title, _ = xutil.GetWMName(wid, workspace.conn)
+	case ipc.EventTitle:
+		event.Title, _ = xutil.GetWMName(wid, workspace.conn)
+	case ipc.EventRemove:
+		clients.Remove(wid, workspace.id)
+	}
+	eventHub.Publish(event)
+}
+
+// Move moves window in the direction specified by message type.
+// Layouts other than the column one swap the window with its neighbour
+func (workspace *Workspace) Move(direction uint, wid uint32) {
+	if !workspace.IsColumns() {
+		win := workspace.FindWindow(wid)
+		if win != nil && !win.IsFloating() {
+			workspace.swap(win, workspace.neighbour(win, direction))
+		}
+		return
+	}
+
+	switch direction {
+	case proto.MoveUp:
+		workspace.MoveUp(wid)
+	case proto.MoveDown:
+		workspace.MoveDown(wid)
+	case proto.MoveLeft:
+		workspace.MoveLeft(wid)
+	case proto.MoveRight:
+		workspace.MoveRight(wid)
+	}
+}
+
+// MoveLeft moves window to the left column.
+// Column left without windows is removed
 func (workspace *Workspace) MoveLeft(wid uint32) {
-	idx := workspace.right.IndexById(wid)
-	if idx > -1 && workspace.right.Len() > 1 {
-		win := workspace.right.WindowByIndex(idx)
-		workspace.right.Remove(win)
-		workspace.left.Add(win)
+	i, idx := workspace.position(wid)
+	if i > 0 {
+		win := workspace.columns[i].WindowByIndex(idx)
+		workspace.removeFromColumn(i, win)
+		workspace.columns[i-1].Add(win)
 	}
 }
 
-// MoveRight moves window to the right column
+// MoveRight moves window to the right column.
+// Column left without windows is removed
 func (workspace *Workspace) MoveRight(wid uint32) {
-	idx := workspace.left.IndexById(wid)
-	if idx > -1 && workspace.left.Len() > 1 {
-		win := workspace.left.WindowByIndex(idx)
-		workspace.left.Remove(win)
-		workspace.right.Add(win)
+	i, idx := workspace.position(wid)
+	if i > -1 && i+1 < len(workspace.columns) {
+		win := workspace.columns[i].WindowByIndex(idx)
+		if workspace.removeFromColumn(i, win) {
+			i--
+		}
+		workspace.columns[i+1].Add(win)
 	}
 }
 
 // MoveUp moves window upward
 func (workspace *Workspace) MoveUp(wid uint32) {
-	idx := workspace.central.IndexById(wid)
-	if idx > -1 {
-		return
+	i, idx := workspace.position(wid)
+	if i > -1 && idx > 0 {
+		workspace.columns[i].Swap(idx, idx-1)
 	}
+}
 
-	idx = workspace.left.IndexById(wid)
-	if idx > 0 {
-		workspace.left.Swap(idx, idx-1)
+// MoveDown moves window downward
+func (workspace *Workspace) MoveDown(wid uint32) {
+	i, idx := workspace.position(wid)
+	if i > -1 && idx < workspace.columns[i].Len()-1 {
+		workspace.columns[i].Swap(idx, idx+1)
 	}
+}
 
-	idx = workspace.right.IndexById(wid)
-	if idx > 0 {
-		workspace.right.Swap(idx, idx-1)
+// ResizeLeft moves left border of the window column to the left.
+// The right border is moved if the column is the first one
+func (workspace *Workspace) ResizeLeft(wid uint32) {
+	i, _ := workspace.position(wid)
+	if i > 0 {
+		workspace.resize(i-1, -config.ResizeStep())
+	} else if i == 0 {
+		workspace.resize(0, -config.ResizeStep())
 	}
 }
 
-// MoveDown moves window downward
-func (workspace *Workspace) MoveDown(wid uint32) {
-	idx := workspace.central.IndexById(wid)
-	if idx > -1 {
+// ResizeRight moves right border of the window column to the right.
+// The left border is moved if the column is the last one
+func (workspace *Workspace) ResizeRight(wid uint32) {
+	i, _ := workspace.position(wid)
+	if i > -1 && i+1 < len(workspace.columns) {
+		workspace.resize(i, config.ResizeStep())
+	} else if i > 0 {
+		workspace.resize(i-1, config.ResizeStep())
+	}
+}
+
+// resize moves the border between the column and the following one
+// by the delta given as a fraction of the workspace width. Neither
+// of the two columns becomes narrower than the minimum column width
+func (workspace *Workspace) resize(i int, delta float64) {
+	if i < 0 || i+1 >= len(workspace.columns) {
 		return
 	}
+	_, _, width, _ := workspace.Area()
+	if width < 1 {
+		return
+	}
+	var sum float64
+	for _, column := range workspace.columns {
+		sum += column.Ratio()
+	}
 
-	idx = workspace.left.IndexById(wid)
-	if idx > -1 && idx < workspace.left.Len()-1 {
-		workspace.left.Swap(idx, idx+1)
+	left, right := workspace.columns[i], workspace.columns[i+1]
+	pair := left.Ratio() + right.Ratio()
+	limit := float64(config.MinColumnWidth()) / float64(width) * sum
+	if pair < 2*limit {
+		return
 	}
+	ratio := math.Max(limit, math.Min(pair-limit, left.Ratio()+delta*sum))
+	left.SetRatio(ratio)
+	right.SetRatio(pair - ratio)
+}
 
-	idx = workspace.right.IndexById(wid)
-	if idx > -1 && idx < workspace.right.Len()-1 {
-		workspace.right.Swap(idx, idx+1)
+// ResizeHeight changes share of the column height taken
+// by the window by the delta, other windows of the column
+// absorb the difference
+func (workspace *Workspace) ResizeHeight(wid uint32, delta float64) {
+	i, idx := workspace.position(wid)
+	if i > -1 {
+		workspace.columns[i].Grow(idx, delta, MinHeightShare)
 	}
 }
 
-// ResizeLeft resizes current window to the left
-func (workspace *Workspace) ResizeLeft(wid uint32) {
-	idx := workspace.right.IndexById(wid)
-	if idx > -1 && workspace.layout == LayoutLeftWide {
-		workspace.layout = LayoutEqual
-		workspace.Reshape()
+// Equalize makes all columns of the workspace equally wide
+// and all windows of every column equally tall
+func (workspace *Workspace) Equalize() {
+	for _, column := range workspace.columns {
+		column.SetRatio(1)
+		column.Equalize()
+	}
+}
+
+// NewColumn moves window to the new column placed to the right
+// of its column, the column shares its width with the new one
+func (workspace *Workspace) NewColumn(wid uint32) {
+	i, idx := workspace.position(wid)
+	if i < 0 || workspace.columns[i].Len() < 2 {
 		return
 	}
+	win := workspace.columns[i].WindowByIndex(idx)
+	workspace.columns[i].Remove(win)
+	workspace.splitColumn(i).Add(win)
+}
 
-	idx = workspace.left.IndexById(wid)
-	if idx > -1 && workspace.layout == LayoutLeftWide {
-		workspace.layout = LayoutEqual
-		workspace.Reshape()
+// MergeColumn moves windows of the window column to the left
+// neighbour, or to the right one if the column is the first one
+func (workspace *Workspace) MergeColumn(wid uint32) {
+	i, _ := workspace.position(wid)
+	if i < 0 || len(workspace.columns) < 2 {
+		return
+	}
+	column := workspace.columns[i]
+	target := i - 1
+	if i == 0 {
+		target = 1
 	}
+	for column.Len() > 0 {
+		win := column.WindowByIndex(0)
+		column.Remove(win)
+		workspace.columns[target].Add(win)
+	}
+	workspace.removeColumn(i)
 }
 
-// ResizeRight resizes current window to the right
-func (workspace *Workspace) ResizeRight(wid uint32) {
-	idx := workspace.left.IndexById(wid)
-	if idx > -1 && workspace.layout == LayoutEqual {
-		workspace.layout = LayoutLeftWide
-		workspace.Reshape()
+// splitColumn inserts new column to the right of the column
+// with the specified index, giving it half of the column width
+func (workspace *Workspace) splitColumn(i int) *Column {
+	column := NewColumn(workspace.screen)
+	ratio := 1.0
+	if i > -1 {
+		ratio = workspace.columns[i].Ratio() / 2
+		workspace.columns[i].SetRatio(ratio)
+	}
+	column.SetRatio(ratio)
+	workspace.columns = append(workspace.columns, nil)
+	copy(workspace.columns[i+2:], workspace.columns[i+1:])
+	workspace.columns[i+1] = column
+	return column
+}
+
+// removeColumn removes column with the specified index,
+// its width is given to the left neighbour or to the right one
+func (workspace *Workspace) removeColumn(i int) {
+	ratio := workspace.columns[i].Ratio()
+	workspace.columns = append(workspace.columns[:i], workspace.columns[i+1:]...)
+	if len(workspace.columns) < 1 {
 		return
 	}
-	idx = workspace.right.IndexById(wid)
-	if idx > -1 && workspace.layout == LayoutEqual {
-		workspace.layout = LayoutLeftWide
-		workspace.Reshape()
+	neighbour := workspace.columns[maxInt(i-1, 0)]
+	neighbour.SetRatio(neighbour.Ratio() + ratio)
+}
+
+// removeFromColumn removes window from the column with the specified
+// index, it reports whether the column is removed being left empty.
+// The window loses its weight in the column
+func (workspace *Workspace) removeFromColumn(i int, window *Window) bool {
+	workspace.columns[i].Remove(window)
+	window.SetWeight(1)
+	if workspace.columns[i].Len() > 0 {
+		return false
+	}
+	workspace.removeColumn(i)
+	return true
+}
+
+// position returns index of the column containing the window and
+// index of the window in the column, -1 is returned if it isn't tiled
+func (workspace *Workspace) position(wid uint32) (int, int) {
+	for i, column := range workspace.columns {
+		if idx := column.IndexById(wid); idx > -1 {
+			return i, idx
+		}
+	}
+	return -1, -1
+}
+
+// neighbour returns tiled window placed next to the window in the
+// direction specified by the move message type. Windows overlapping
+// the window across the direction are preferred, the nearest one
+// is chosen. The window itself is returned if there is no neighbour
+func (workspace *Workspace) neighbour(window *Window, direction uint) *Window {
+	dx, dy := 0, 0
+	switch direction {
+	case proto.MoveUp:
+		dy = -1
+	case proto.MoveDown:
+		dy = 1
+	case proto.MoveLeft:
+		dx = -1
+	case proto.MoveRight:
+		dx = 1
 	}
+
+	cx, cy := window.x+window.width/2, window.y+window.height/2
+	best, bestOverlap, bestScore := window, false, 0
+	for _, win := range workspace.Tiled() {
+		if win.Id() == window.Id() || win.IsFullscreen() {
+			continue
+		}
+		wx, wy := win.x+win.width/2, win.y+win.height/2
+		along := dx*(wx-cx) + dy*(wy-cy)
+		if along <= 0 {
+			continue
+		}
+		var across int
+		var overlap bool
+		if dx != 0 {
+			across = wy - cy
+			overlap = win.y < window.y+window.height && window.y < win.y+win.height
+		} else {
+			across = wx - cx
+			overlap = win.x < window.x+window.width && window.x < win.x+win.width
+		}
+		if across < 0 {
+			across = -across
+		}
+		score := along + across
+		if best == window || (overlap && !bestOverlap) ||
+			(overlap == bestOverlap && score < bestScore) {
+			best, bestOverlap, bestScore = win, overlap, score
+		}
+	}
+	return best
 }
 
-// Add adds new window to the workspace
+// swap exchanges places of two tiled windows
+func (workspace *Workspace) swap(a, b *Window) {
+	if b == nil || a.Id() == b.Id() {
+		return
+	}
+	i, idx := workspace.position(a.Id())
+	j, jdx := workspace.position(b.Id())
+	if i < 0 || j < 0 {
+		return
+	}
+	workspace.columns[i].windows[idx] = b
+	workspace.columns[j].windows[jdx] = a
+}
+
+// Layout returns name of the workspace layout
+func (workspace *Workspace) Layout() string {
+	return workspace.layout.Name()
+}
+
+// Sizing describes width of the columns of the column layout,
+// it is empty for other layouts
+func (workspace *Workspace) Sizing() string {
+	if !workspace.IsColumns() {
+		return ""
+	}
+	for _, column := range workspace.columns {
+		ratio := workspace.columns[0].Ratio()
+		if math.Abs(column.Ratio()-ratio) > 0.01*math.Max(column.Ratio(), ratio) {
+			return SizingCustom
+		}
+	}
+	if len(workspace.columns) > 1 {
+		return SizingEqual
+	}
+	return SizingFull
+}
+
+// SetLayout changes layout placing tiled windows of the workspace
+func (workspace *Workspace) SetLayout(layout Layout) {
+	workspace.layout = layout
+}
+
+// IsColumns checks whether the workspace uses the column layout
+func (workspace *Workspace) IsColumns() bool {
+	return workspace.layout.Name() == LayoutColumns
+}
+
+// IsSingle checks whether the workspace has the only tiled window
+func (workspace *Workspace) IsSingle() bool {
+	return len(workspace.columns) == 1 && workspace.columns[0].Len() == 1
+}
+
+// Add adds new tiled window to the workspace according to its layout.
+// The first tiled window gets focus
 func (workspace *Workspace) Add(window *Window) {
-	leftEmpty := workspace.left.Len() < 1
-	rightEmpty := workspace.right.Len() < 1
-	centralEmpty := workspace.central.Len() < 1
+	if len(workspace.columns) < 1 {
+		workspace.focus = window
+	}
+	workspace.layout.Add(workspace, window)
+}
+
+// Release removes window from the workspace without unmapping it.
+// The next window gets focus only if the workspace is visible
+func (workspace *Workspace) Release(window *Window) {
+	if workspace.focus != nil && window.Id() == workspace.focus.Id() {
+		window.Defocus()
+		workspace.Refocus()
+	}
+	workspace.Remove(window)
+	workspace.Reshape()
+	workspace.Focus()
+	workspace.publish(ipc.EventRemove, window.Id())
+}
 
-	if leftEmpty && rightEmpty && centralEmpty {
-		workspace.central.Add(window)
+// AddFloating adds new window keeping its own size.
+// The window is centered over the parent if it belongs to the workspace
+func (workspace *Workspace) AddFloating(window *Window, parent uint32) {
+	window.SetFloating()
+	workspace.Center(window, workspace.FindWindow(parent))
+	workspace.floating.Add(window)
+	if workspace.focus == nil {
+		workspace.focus = window
+	}
+}
+
+// ToggleFloating switches window between tiled and floating
+func (workspace *Workspace) ToggleFloating(window *Window) {
+	if window.IsFloating() {
+		workspace.floating.Remove(window)
+		window.SetTiled()
+		workspace.Add(window)
 		workspace.focus = window
-		workspace.layout = LayoutFull
 		return
 	}
 
-	if workspace.layout == LayoutFull {
-		workspace.layout = DefaultLayout
+	workspace.Float(window)
+	// Floating window shouldn't hide the tiles completely
+	_, _, width, height := workspace.Area()
+	window.width = minInt(window.width, width*2/3)
+	window.height = minInt(window.height, height*2/3)
+	workspace.Center(window, nil)
+	workspace.focus = window
+}
+
+// Float moves tiled window to the floating windows keeping the focus
+func (workspace *Workspace) Float(window *Window) {
+	focus := workspace.focus
+	workspace.Remove(window)
+	window.SetFloating()
+	workspace.floating.Add(window)
+	if focus != nil {
+		workspace.focus = focus
 	}
+}
 
-	if !centralEmpty && leftEmpty && rightEmpty {
-		win := workspace.central.WindowByIndex(0)
-		workspace.left.Add(win)
-		workspace.right.Add(window)
-		workspace.central.Remove(win)
-	} else {
-		workspace.right.Add(window)
+// Center places window at the center of the parent or of the screen
+// if there is no parent. The window is shrunk to fit the screen
+func (workspace *Workspace) Center(window, parent *Window) {
+	x, y, width, height := workspace.Area()
+	w, h := minInt(window.width, width), minInt(window.height, height)
+	px, py, pwidth, pheight := x, y, width, height
+	if parent != nil {
+		px, py, pwidth, pheight = parent.x, parent.y, parent.width, parent.height
 	}
+
+	wx := maxInt(x, minInt(px+(pwidth-w)/2, x+width-w))
+	wy := maxInt(y, minInt(py+(pheight-h)/2, y+height-h))
+	window.Move(wx, wy, w, h)
 }
 
-// Remove removes window from the workspace
-func (workspace *Workspace) Remove(window *Window) {
-	if window == nil {
-		return
+// Area returns position and size of the screen region
+// available for windows of the workspace
+func (workspace *Workspace) Area() (x, y, width, height int) {
+	return workspace.screen.WorkArea()
+}
+
+// Restack puts floating windows above the tiled ones
+// and fullscreen windows above all others
+func (workspace *Workspace) Restack() {
+	workspace.raiseFloating()
+	for _, win := range workspace.Windows() {
+		if win.IsFullscreen() {
+			win.Raise()
+		}
 	}
-	nleft := workspace.left.Len()
-	nright := workspace.right.Len()
+}
 
-	inLeft, inRight, inCentral := false, false, false
-	inCentral = workspace.central.IndexById(window.Id()) > -1
-	if !inCentral {
-		inLeft = workspace.left.IndexById(window.Id()) > -1
+// raiseFloating puts floating windows above the tiled ones
+func (workspace *Workspace) raiseFloating() {
+	for i := 0; i < workspace.floating.Len(); i++ {
+		workspace.floating.WindowByIndex(i).Raise()
 	}
-	if !inCentral && !inLeft {
-		inRight = workspace.right.IndexById(window.Id()) > -1
+}
+
+// Tiled returns tiled windows of the workspace column by column
+func (workspace *Workspace) Tiled() []*Window {
+	var windows []*Window
+	for _, column := range workspace.columns {
+		windows = append(windows, column.windows...)
 	}
+	return windows
+}
 
-	if inLeft && nleft == 1 && nright == 1 {
-		win := workspace.right.WindowByIndex(0)
-		workspace.right.Remove(win)
-		workspace.central.Add(win)
-		workspace.layout = LayoutFull
+// Windows returns all windows of the workspace,
+// tiled windows go first and floating ones follow
+func (workspace *Workspace) Windows() []*Window {
+	var windows []*Window
+	for _, column := range append(workspace.columns, workspace.floating) {
+		for i := 0; i < column.Len(); i++ {
+			windows = append(windows, column.WindowByIndex(i))
+		}
 	}
+	return windows
+}
 
-	if inRight && nright == 1 && nleft == 1 {
-		win := workspace.left.WindowByIndex(0)
-		workspace.left.Remove(win)
-		workspace.central.Add(win)
-		workspace.layout = LayoutFull
+// FocusNext returns window following the focused one,
+// tiled windows go first and floating ones follow
+func (workspace *Workspace) FocusNext() *Window {
+	if workspace.focus == nil {
+		return nil
 	}
 
-	if inLeft && nleft == 1 && nright > 1 {
-		win := workspace.right.WindowByIndex(0)
-		workspace.left.Add(win)
-		workspace.right.Remove(win)
+	windows := workspace.Windows()
+	for i, win := range windows {
+		if win.Id() == workspace.focus.Id() {
+			return windows[(i+1)%len(windows)]
+		}
 	}
+	return workspace.focus
+}
 
-	if inRight && nright == 1 && nleft > 1 {
-		win := workspace.left.WindowByIndex(0)
-		workspace.right.Add(win)
-		workspace.left.Remove(win)
+// AddToColumn adds new window to the column specified by its number
+// counting from 1. Position "left" stands for the first column and
+// "right" for the last one. New column is created if the workspace has
+// fewer columns or the only one. Window is added as usual if position
+// is any other one
+func (workspace *Workspace) AddToColumn(window *Window, position string) {
+	n := len(workspace.columns)
+	i, err := strconv.Atoi(position)
+	switch {
+	case err == nil && i > 0:
+		i--
+	case position == "left":
+		i = 0
+	case position == "right":
+		i = maxInt(n, 2) - 1
+	default:
+		i = -1
+	}
+	if n < 1 || i < 0 {
+		workspace.Add(window)
+		return
 	}
 
 	switch {
-	case inCentral:
-		workspace.central.Remove(window)
-		workspace.focus = nil
-		workspace.layout = LayoutFull
-		workspace.ChangeName()
-	case inRight:
-		workspace.right.Remove(window)
+	case i >= n:
+		workspace.splitColumn(n - 1).Add(window)
+	case n == 1 && i == 0:
+		column := workspace.splitColumn(-1)
+		column.SetRatio(workspace.columns[1].Ratio())
+		column.Add(window)
 	default:
-		workspace.left.Remove(window)
+		workspace.columns[i].Add(window)
 	}
 }
 
-// Focus changes focus to current focus window
+// Restore places windows to the columns according to the arrangement.
+// Tiled columns keep their order and proportions of their widths
+func (workspace *Workspace) Restore(arrangement Arrangement) {
+	for _, ws := range workspace.arrange(arrangement.State) {
+		win := workspace.FindWindow(ws.ID)
+		win.LoadHints()
+		if arrangement.Hide && xutil.IsViewable(ws.ID, workspace.conn) {
+			win.Hide()
+		}
+		if win.IsFloating() {
+			win.SetFloating()
+		}
+		if ws.Fullscreen {
+			win.SetFullscreen(true)
+		}
+		workspace.publish(ipc.EventAttach, win.Id())
+	}
+}
+
+// arrange sets layout, columns and focus of the workspace
+// according to the state and returns states of the added windows
+func (workspace *Workspace) arrange(state ipc.WorkspaceState) []ipc.WindowState {
+	if layout, ok := LayoutByName(state.Layout); ok {
+		workspace.layout = layout
+	}
+	var added []ipc.WindowState
+	var tiled []*Window
+	for _, cs := range state.Columns {
+		column := workspace.floating
+		if cs.Position != ipc.PositionFloating {
+			column = NewColumn(workspace.screen)
+			if cs.Ratio > 0 {
+				column.SetRatio(cs.Ratio)
+			}
+		}
+		for _, ws := range cs.Windows {
+			if workspace.FindWindow(ws.ID) != nil || column.IndexById(ws.ID) > -1 {
+				continue
+			}
+			win := NewWindow(ws.ID, workspace.headc, workspace.conn)
+			if ws.Weight > 0 {
+				win.SetWeight(ws.Weight)
+			}
+			if column == workspace.floating {
+				win.floating = true
+			} else {
+				tiled = append(tiled, win)
+			}
+			column.Add(win)
+			added = append(added, ws)
+			if ws.ID == state.Focus {
+				workspace.focus = win
+			}
+		}
+		if column != workspace.floating && column.Len() > 0 {
+			workspace.columns = append(workspace.columns, column)
+		}
+	}
+
+	if workspace.focus == nil && len(tiled) > 0 {
+		workspace.focus = tiled[0]
+	} else if workspace.focus == nil {
+		workspace.focus = workspace.floating.WindowByIndex(0)
+	}
+	return added
+}
+
+// Remove removes window from the workspace.
+// Column left without windows is removed
+func (workspace *Workspace) Remove(window *Window) {
+	if window == nil {
+		return
+	}
+	if workspace.focus != nil && workspace.focus.Id() == window.Id() {
+		workspace.focus = nil
+	}
+	if workspace.floating.Remove(window) != nil {
+		return
+	}
+	workspace.layout.Remove(workspace, window)
+}
+
+// Focus changes focus to current focus window. Windows of the
+// workspace which isn't shown on a monitor can't take focus
 func (workspace *Workspace) Focus() {
-	if workspace.focus == nil {
+	if workspace.focus == nil || !workspace.visible {
 		return
 	}
 
 	workspace.focus.TakeFocus()
-
-	if workspace.central.Len() > 0 {
+	workspace.raiseFocus()
+	if workspace.IsSingle() {
 		workspace.focus.UnsetBorder()
 	}
 }
 
+// raiseFocus raises focused window if it may be covered by others.
+// Fullscreen windows stay above the focused tile
+func (workspace *Workspace) raiseFocus() {
+	switch {
+	case workspace.focus.IsFloating() || workspace.focus.IsFullscreen():
+		workspace.focus.Raise()
+	case workspace.layout.Name() == LayoutMonocle:
+		// Focused tile shouldn't stay behind the other tiles
+		workspace.focus.Raise()
+		workspace.Restack()
+	}
+}
+
 // Refocus finds new focus window
 func (workspace *Workspace) Refocus() {
 	if workspace.focus == nil {
@@ -460,6 +1058,13 @@ func (workspace *Workspace) Refocus() {
 		return
 	}
 
+	for _, win := range workspace.Windows() {
+		if win.Id() != workspace.focus.Id() {
+			workspace.focus = win
+			return
+		}
+	}
+
 	workspace.focus = nil
 }
 
@@ -468,22 +1073,14 @@ func (workspace *Workspace) FocusDown() *Window {
 	if workspace.focus == nil {
 		return nil
 	}
-
-	win := workspace.central.WindowByIndex(0)
-	if win != nil && win.Id() == workspace.focus.Id() {
-		return workspace.focus
-	}
-
-	idx := workspace.left.IndexById(workspace.focus.Id())
-	if idx > -1 && idx+1 < workspace.left.Len() {
-		return workspace.left.WindowByIndex(idx + 1)
+	if !workspace.IsColumns() {
+		return workspace.neighbour(workspace.focus, proto.MoveDown)
 	}
 
-	idx = workspace.right.IndexById(workspace.focus.Id())
-	if idx > -1 && idx+1 < workspace.right.Len() {
-		return workspace.right.WindowByIndex(idx + 1)
+	i, idx := workspace.position(workspace.focus.Id())
+	if i > -1 && idx+1 < workspace.columns[i].Len() {
+		return workspace.columns[i].WindowByIndex(idx + 1)
 	}
-
 	return workspace.focus
 }
 
@@ -492,22 +1089,14 @@ func (workspace *Workspace) FocusUp() *Window {
 	if workspace.focus == nil {
 		return nil
 	}
-
-	win := workspace.central.WindowByIndex(0)
-	if win != nil && win.Id() == workspace.focus.Id() {
-		return workspace.focus
-	}
-
-	idx := workspace.left.IndexById(workspace.focus.Id())
-	if idx > -1 && idx-1 >= 0 {
-		return workspace.left.WindowByIndex(idx - 1)
+	if !workspace.IsColumns() {
+		return workspace.neighbour(workspace.focus, proto.MoveUp)
 	}
 
-	idx = workspace.right.IndexById(workspace.focus.Id())
-	if idx > -1 && idx-1 >= 0 {
-		return workspace.right.WindowByIndex(idx - 1)
+	i, idx := workspace.position(workspace.focus.Id())
+	if i > -1 && idx > 0 {
+		return workspace.columns[i].WindowByIndex(idx - 1)
 	}
-
 	return workspace.focus
 }
 
@@ -516,18 +1105,16 @@ func (workspace *Workspace) FocusLeft() *Window {
 	if workspace.focus == nil {
 		return nil
 	}
-
-	win := workspace.central.WindowByIndex(0)
-	if win != nil && win.Id() == workspace.focus.Id() {
-		return workspace.focus
+	if !workspace.IsColumns() {
+		return workspace.neighbour(workspace.focus, proto.MoveLeft)
 	}
 
-	idx := workspace.left.IndexById(workspace.focus.Id())
-	if idx > -1 {
+	i, idx := workspace.position(workspace.focus.Id())
+	if i < 1 {
 		return workspace.focus
 	}
-
-	return workspace.left.WindowByIndex(0)
+	column := workspace.columns[i-1]
+	return column.WindowByIndex(minInt(idx, column.Len()-1))
 }
 
 // FocusRight changes focus to the right
@@ -535,50 +1122,31 @@ func (workspace *Workspace) FocusRight() *Window {
 	if workspace.focus == nil {
 		return nil
 	}
-
-	win := workspace.central.WindowByIndex(0)
-	if win != nil && win.Id() == workspace.focus.Id() {
-		return workspace.focus
+	if !workspace.IsColumns() {
+		return workspace.neighbour(workspace.focus, proto.MoveRight)
 	}
 
-	idx := workspace.right.IndexById(workspace.focus.Id())
-	if idx > -1 {
+	i, idx := workspace.position(workspace.focus.Id())
+	if i < 0 || i+1 >= len(workspace.columns) {
 		return workspace.focus
 	}
-
-	return workspace.right.WindowByIndex(0)
+	column := workspace.columns[i+1]
+	return column.WindowByIndex(minInt(idx, column.Len()-1))
 }
 
 // Activate makes workspace active, making all its windows visible
 func (workspace *Workspace) Activate() {
-	for i := 0; i < workspace.central.Len(); i++ {
-		win := workspace.central.WindowByIndex(i)
-		win.Map()
-	}
-	for i := 0; i < workspace.left.Len(); i++ {
-		win := workspace.left.WindowByIndex(i)
-		win.Map()
-	}
-	for i := 0; i < workspace.right.Len(); i++ {
-		win := workspace.right.WindowByIndex(i)
+	workspace.visible = true
+	for _, win := range workspace.Windows() {
 		win.Map()
 	}
+	workspace.Restack()
 }
 
-// Deactivate makes workspace active, making all its windows invisible
+// Deactivate makes workspace inactive, making all its windows invisible
 func (workspace *Workspace) Deactivate() {
-	for i := 0; i < workspace.central.Len(); i++ {
-		win := workspace.central.WindowByIndex(i)
-		win.DenyRemoval()
-		win.Unmap()
-	}
-	for i := 0; i < workspace.left.Len(); i++ {
-		win := workspace.left.WindowByIndex(i)
-		win.DenyRemoval()
-		win.Unmap()
-	}
-	for i := 0; i < workspace.right.Len(); i++ {
-		win := workspace.right.WindowByIndex(i)
+	workspace.visible = false
+	for _, win := range workspace.Windows() {
 		win.DenyRemoval()
 		win.Unmap()
 	}
@@ -586,41 +1154,47 @@ func (workspace *Workspace) Deactivate() {
 
 // FindWindow searches window by its identifier
 func (workspace *Workspace) FindWindow(wid uint32) *Window {
-	if idx := workspace.central.IndexById(wid); idx > -1 {
-		return workspace.central.WindowByIndex(idx)
+	for _, column := range append(workspace.columns, workspace.floating) {
+		if idx := column.IndexById(wid); idx > -1 {
+			return column.WindowByIndex(idx)
+		}
 	}
+	return nil
+}
 
-	if idx := workspace.left.IndexById(wid); idx > -1 {
-		return workspace.left.WindowByIndex(idx)
+// Reshape changes window sizes according to current layout.
+// Windows whose minimum size doesn't fit their slots become floating
+func (workspace *Workspace) Reshape() {
+	x, y, width, height := workspace.Area()
+	for {
+		unfit := workspace.layout.Arrange(workspace, Rect{x, y, width, height})
+		if len(unfit) < 1 {
+			break
+		}
+		for _, win := range unfit {
+			workspace.Float(win)
+			win.width = maxInt(win.width, win.hints.MinWidth)
+			win.height = maxInt(win.height, win.hints.MinHeight)
+			workspace.Center(win, nil)
+		}
+		workspace.Restack()
 	}
 
-	if idx := workspace.right.IndexById(wid); idx > -1 {
-		return workspace.right.WindowByIndex(idx)
+	// Fullscreen windows cover the whole monitor including paddings
+	screen := workspace.screen
+	for _, win := range workspace.Windows() {
+		if win.IsFullscreen() {
+			win.Move(screen.XOffset(), screen.YOffset(), screen.Width(), screen.Height())
+		}
 	}
-
-	return nil
 }
 
-// Reshape changes window sizes according to current layout
-func (workspace *Workspace) Reshape() {
-	if workspace.central.Len() > 0 {
-		workspace.central.SetX(0)
-		workspace.central.SetWidth100()
-	} else if workspace.layout == LayoutEqual {
-		workspace.left.SetX(0)
-		x := workspace.left.SetWidth50()
-		workspace.right.SetX(x)
-		workspace.right.SetWidth50()
-	} else {
-		workspace.left.SetX(0)
-		x := workspace.left.SetWidth65()
-		workspace.right.SetX(x)
-		workspace.right.SetWidth35()
+// SetScreen moves all columns of the workspace to the screen
+func (workspace *Workspace) SetScreen(screen xutil.Screen) {
+	workspace.screen = screen
+	for _, column := range append(workspace.columns, workspace.floating) {
+		column.SetScreen(screen)
 	}
-
-	workspace.central.Reshape()
-	workspace.left.Reshape()
-	workspace.right.Reshape()
 }
 
 // ChangeName changes name of the workspace according
@@ -632,8 +1206,7 @@ func (workspace *Workspace) ChangeName() {
 
 	repr := fmt.Sprintf("%d", workspace.id)
 	if workspace.focus != nil {
-		n := workspace.left.Len() + workspace.right.Len()
-		n += workspace.central.Len()
+		n := len(workspace.Windows())
 		name, err := xutil.GetWMName(
 			workspace.focus.Id(), workspace.conn,
 		)
@@ -658,6 +1231,22 @@ func (workspace *Workspace) ChangeName() {
 	xutil.SetDesktopNames(names, workspace.conn)
 }
 
+// State returns description of the workspace and its columns
+func (workspace *Workspace) State() ipc.WorkspaceState {
+	focus := workspace.focusId()
+	columns := make([]ipc.ColumnState, 0, len(workspace.columns)+1)
+	for i, column := range workspace.columns {
+		columns = append(columns, column.State(strconv.Itoa(i+1), focus))
+	}
+	return ipc.WorkspaceState{
+		ID:      workspace.id,
+		Layout:  workspace.Layout(),
+		Sizing:  workspace.Sizing(),
+		Focus:   focus,
+		Columns: append(columns, workspace.floating.State(ipc.PositionFloating, focus)),
+	}
+}
+
 // LogStatus logs workspace's information for debugging purposes
 func (workspace *Workspace) LogStatus() {
 	logging.Print("Workspace ID", workspace.id, " ")
@@ -667,47 +1256,55 @@ func (workspace *Workspace) LogStatus() {
 		logging.Println("focus = nil")
 	}
 
-	logging.Println("Central ")
-	workspace.central.LogStatus()
-	logging.Println("Left ")
-	workspace.left.LogStatus()
-	logging.Println("Right ")
-	workspace.right.LogStatus()
+	for i, column := range workspace.columns {
+		logging.Println("Column", i+1)
+		column.LogStatus()
+	}
+	logging.Println("Floating ")
+	workspace.floating.LogStatus()
 	logging.Print("\n\n")
 }
 
 // WorkspaceManager represents a logical bridge
 // between windows and workspaces
 type WorkspaceManager struct {
-	prev    uint32
-	curr    uint32
-	mailbox chan proto.Message
+	// visible holds workspaces shown on the monitors in their order
+	visible  []uint32
+	monitor  int
+	mailbox  chan proto.Message
+	monitors xutil.MonitorsInfo
+	count    uint32
 }
 
-// NewWorkspaceManager creates instance of WorkspaceManager
+// NewWorkspaceManager creates instance of WorkspaceManager.
+// The primary monitor shows the default workspace,
+// other monitors show the last workspaces
 func NewWorkspaceManager(monitors xutil.MonitorsInfo) *WorkspaceManager {
-	input := make(chan proto.Message)
-	next := make(chan proto.Message)
-	mailbox := input
-
-	for j := uint32(1); j < MaxWorkspaces-1; j++ {
-		w := NewWorkspace(mailbox, input, next, j, monitors.Primary())
-		go w.Run()
-		input = next
-		next = make(chan proto.Message)
+	count := uint32(MaxWorkspaces - 1)
+	if monitors.Len() > 1 {
+		count = MaxWorkspaces
+	}
+	wrkmgr := &WorkspaceManager{
+		visible:  []uint32{DefaultWorkspace},
+		mailbox:  make(chan proto.Message),
+		monitors: monitors,
+		count:    count,
+	}
+	n := minInt(monitors.Len(), int(count))
+	for i := 1; i < n; i++ {
+		wrkmgr.visible = append(wrkmgr.visible, count-uint32(n-1-i))
 	}
 
-	if !monitors.IsDualSetup() {
-		w := NewWorkspace(mailbox, input, nil, MaxWorkspaces-1, monitors.Primary())
-		go w.Run()
-	} else {
-		w := NewWorkspace(mailbox, input, next, MaxWorkspaces-1, monitors.Primary())
-		go w.Run()
-		w = NewWorkspace(mailbox, next, nil, MaxWorkspaces, monitors.Secondary())
+	input, next := wrkmgr.mailbox, make(chan proto.Message)
+	for id := uint32(1); id <= count; id++ {
+		if id == count {
+			next = nil
+		}
+		w := NewWorkspace(wrkmgr.mailbox, input, next, id, wrkmgr.Screen(id))
 		go w.Run()
+		input, next = next, make(chan proto.Message)
 	}
-
-	return &WorkspaceManager{DefaultWorkspace, DefaultWorkspace, mailbox}
+	return wrkmgr
 }
 
 // Mailbox returns channel, that is used for passing messages to workspaces
@@ -715,25 +1312,149 @@ func (wrkmgr *WorkspaceManager) Mailbox() chan proto.Message {
 	return wrkmgr.mailbox
 }
 
-// Prev returns id of previously active workspace
-func (wrkmgr WorkspaceManager) Prev() uint32 {
-	return wrkmgr.prev
-}
-
-// Curr return id of currently active workspace
+// Curr return id of currently active workspace,
+// i.e. workspace shown on the focused monitor
 func (wrkmgr *WorkspaceManager) Curr() uint32 {
-	return wrkmgr.curr
+	return wrkmgr.visible[wrkmgr.monitor]
 }
 
-// SetCurr sets current active workspace
+// SetCurr sets current active workspace. Workspace shown on another
+// monitor becomes active focusing that monitor, hidden workspace
+// replaces the one shown on the focused monitor
 func (wrkmgr *WorkspaceManager) SetCurr(n uint32) {
-	if wrkmgr.curr != n {
-		wrkmgr.prev = wrkmgr.curr
+	if wrkmgr.Curr() == n {
+		return
+	}
+	if m := wrkmgr.MonitorOf(n); m >= 0 {
+		wrkmgr.monitor = m
+	} else {
+		wrkmgr.visible[wrkmgr.monitor] = n
+	}
+	eventHub.Publish(ipc.Event{Event: ipc.EventWorkspace, Workspace: n})
+}
+
+// Monitor returns index of the focused monitor
+func (wrkmgr *WorkspaceManager) Monitor() int {
+	return wrkmgr.monitor
+}
+
+// MonitorOf returns index of the monitor showing
+// the workspace, -1 is returned for hidden workspaces
+func (wrkmgr *WorkspaceManager) MonitorOf(id uint32) int {
+	for m, visible := range wrkmgr.visible {
+		if visible == id {
+			return m
+		}
+	}
+	return -1
+}
+
+// VisibleOn returns id of the workspace shown on the monitor
+func (wrkmgr *WorkspaceManager) VisibleOn(monitor int) uint32 {
+	return wrkmgr.visible[monitor]
+}
+
+// VisibleAll returns ids of the workspaces shown on the monitors
+func (wrkmgr *WorkspaceManager) VisibleAll() []uint32 {
+	return append([]uint32(nil), wrkmgr.visible...)
+}
+
+// SetVisible sets workspaces shown on the monitors in their order.
+// Invalid and repeated ids are ignored, e.g. when the number
+// of monitors changed, monitors left keep their workspaces
+func (wrkmgr *WorkspaceManager) SetVisible(ids []uint32) {
+	for m, id := range ids {
+		if m >= len(wrkmgr.visible) || id < 1 || id > wrkmgr.count {
+			continue
+		}
+		if other := wrkmgr.MonitorOf(id); other >= 0 {
+			wrkmgr.visible[other] = wrkmgr.visible[m]
+		}
+		wrkmgr.visible[m] = id
+	}
+}
+
+// Tree queries state of all workspaces
+func (wrkmgr *WorkspaceManager) Tree(conn *xgb.Conn) ipc.Tree {
+	tree := ipc.Tree{Current: wrkmgr.Curr(), Visible: wrkmgr.VisibleAll()}
+	win := NewWindow(0, wrkmgr.mailbox, conn)
+	for id := uint32(1); id <= wrkmgr.count; id++ {
+		reply := make(chan ipc.WorkspaceState, 1)
+		win.SendQuery(id, reply)
+		tree.Workspaces = append(tree.Workspaces, <-reply)
 	}
-	wrkmgr.curr = n
+	return tree
 }
 
-// SpecialWorkspace returns id of special workspace, used for external monitor
-func (wrkmgr WorkspaceManager) SpecialWorkspace() uint32 {
-	return MaxWorkspaces
+// Monitors returns information about connected monitors
+func (wrkmgr *WorkspaceManager) Monitors() xutil.MonitorsInfo {
+	return wrkmgr.monitors
+}
+
+// SetMonitors updates information about connected monitors and returns
+// workspaces which should be hidden or shown. Workspaces of the removed
+// monitors become hidden and focus moves to the primary monitor if the
+// focused one is removed. Added monitors show the last hidden workspaces.
+// The number of workspaces stays the same as at the startup
+func (wrkmgr *WorkspaceManager) SetMonitors(
+	monitors xutil.MonitorsInfo,
+) (hidden, shown []uint32) {
+	curr := wrkmgr.Curr()
+	wrkmgr.monitors = monitors
+	n := minInt(monitors.Len(), int(wrkmgr.count))
+	if n < len(wrkmgr.visible) {
+		hidden = append(hidden, wrkmgr.visible[n:]...)
+		wrkmgr.visible = wrkmgr.visible[:n]
+	}
+	for id := wrkmgr.count; id > 0 && len(wrkmgr.visible) < n; id-- {
+		if !wrkmgr.Visible(id) {
+			wrkmgr.visible = append(wrkmgr.visible, id)
+			shown = append(shown, id)
+		}
+	}
+
+	if wrkmgr.monitor >= len(wrkmgr.visible) {
+		wrkmgr.monitor = 0
+	}
+	if wrkmgr.Curr() != curr {
+		eventHub.Publish(ipc.Event{Event: ipc.EventWorkspace, Workspace: wrkmgr.Curr()})
+	}
+	return hidden, shown
+}
+
+// Screen returns screen on which workspace with the specified id
+// is placed. Hidden workspaces are placed on the focused monitor
+func (wrkmgr *WorkspaceManager) Screen(id uint32) xutil.Screen {
+	m := wrkmgr.MonitorOf(id)
+	if m < 0 {
+		m = wrkmgr.monitor
+	}
+	if m >= wrkmgr.monitors.Len() {
+		return wrkmgr.monitors.Primary()
+	}
+	return wrkmgr.monitors.Screen(m)
+}
+
+// Workspaces returns the number of workspaces
+func (wrkmgr *WorkspaceManager) Workspaces() uint32 {
+	return wrkmgr.count
+}
+
+// Visible checks whether workspace with the specified id is shown on a monitor
+func (wrkmgr *WorkspaceManager) Visible(id uint32) bool {
+	return wrkmgr.MonitorOf(id) >= 0
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
 }
@@ -3,6 +3,7 @@ package xutil
 
 import (
 	"errors"
+	"strings"
 
 	"github.com/BurntSushi/xgb"
 	"github.com/BurntSushi/xgb/xproto"
@@ -17,13 +18,41 @@ func getRoot(conn *xgb.Conn) (xproto.Window, error) {
 	return coninfo.Roots[0].Root, nil
 }
 
+// SetRootBackground changes background color of the root window
+func SetRootBackground(color uint32, conn *xgb.Conn) error {
+	root, err := getRoot(conn)
+	if err != nil {
+		return err
+	}
+	err = ChangeWindowAttributesChecked(
+		conn, root, xproto.CwBackPixel, []uint32{color},
+	).Check()
+	if err != nil {
+		return err
+	}
+	return xproto.ClearAreaChecked(conn, false, root, 0, 0, 0, 0).Check()
+}
+
 // SetSupported sets supported hints
 func SetSupported(conn *xgb.Conn) error {
 	atoms := []xproto.Atom{
 		GetAtom("_NET_SUPPORTED", conn),
+		GetAtom("_NET_SUPPORTING_WM_CHECK", conn),
+		GetAtom("_NET_WM_NAME", conn),
 		GetAtom("_NET_NUMBER_OF_DESKTOPS", conn),
 		GetAtom("_NET_DESKTOP_NAMES", conn),
 		GetAtom("_NET_CURRENT_DESKTOP", conn),
+		GetAtom("_NET_CLIENT_LIST", conn),
+		GetAtom("_NET_CLIENT_LIST_STACKING", conn),
+		GetAtom("_NET_ACTIVE_WINDOW", conn),
+		GetAtom("_NET_WM_DESKTOP", conn),
+		GetAtom("_NET_CLOSE_WINDOW", conn),
+		GetAtom("_NET_MOVERESIZE_WINDOW", conn),
+		GetAtom("_NET_WM_STATE", conn),
+		GetAtom("_NET_WM_STATE_FULLSCREEN", conn),
+		GetAtom("_NET_WORKAREA", conn),
+		GetAtom("_NET_WM_STRUT", conn),
+		GetAtom("_NET_WM_STRUT_PARTIAL", conn),
 	}
 	buf := make([]byte, len(atoms)*4)
 	for i, atom := range atoms {
@@ -41,6 +70,54 @@ func SetSupported(conn *xgb.Conn) error {
 	return err
 }
 
+// CreateCheckWindow creates the never mapped child window of the root,
+// which identifies the window manager and owns the manager selection
+func CreateCheckWindow(conn *xgb.Conn) (uint32, error) {
+	root, err := getRoot(conn)
+	if err != nil {
+		return 0, err
+	}
+	wid, err := xproto.NewWindowId(conn)
+	if err != nil {
+		return 0, err
+	}
+	err = xproto.CreateWindowChecked(
+		conn, 0, wid, root, -1, -1, 1, 1, 0,
+		xproto.WindowClassInputOnly, 0,
+		xproto.CwOverrideRedirect|xproto.CwEventMask,
+		[]uint32{1, xproto.EventMaskPropertyChange},
+	).Check()
+	return uint32(wid), err
+}
+
+// SetCheckWindow sets _NET_SUPPORTING_WM_CHECK property of the root
+// window and of the check window itself, the check window gets
+// _NET_WM_NAME property with the specified name
+func SetCheckWindow(checkWid uint32, name string, conn *xgb.Conn) error {
+	root, err := getRoot(conn)
+	if err != nil {
+		return err
+	}
+	wid := xproto.Window(checkWid)
+	buf := make([]byte, 4)
+	xgb.Put32(buf, uint32(wid))
+	check := GetAtom("_NET_SUPPORTING_WM_CHECK", conn)
+	for _, window := range []xproto.Window{root, wid} {
+		err = xproto.ChangePropertyChecked(
+			conn, xproto.PropModeReplace, window, check,
+			xproto.AtomWindow, 32, 1, buf,
+		).Check()
+		if err != nil {
+			return err
+		}
+	}
+
+	return xproto.ChangePropertyChecked(
+		conn, xproto.PropModeReplace, wid, GetAtom("_NET_WM_NAME", conn),
+		GetAtom("UTF8_STRING", conn), 8, uint32(len(name)), []byte(name),
+	).Check()
+}
+
 // SetNumberOfDesktops sets total number of desktops (workspaces)
 func SetNumberOfDesktops(n uint32, conn *xgb.Conn) error {
 	root, err := getRoot(conn)
@@ -73,6 +150,55 @@ func SetCurrentDesktop(n uint32, conn *xgb.Conn) error {
 	return err
 }
 
+// SetWorkArea sets _NET_WORKAREA property, which holds
+// position and size of the work area of every desktop
+func SetWorkArea(areas [][4]int, conn *xgb.Conn) error {
+	root, err := getRoot(conn)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, len(areas)*16)
+	for i, area := range areas {
+		for j, value := range area {
+			xgb.Put32(buf[i*16+j*4:], uint32(value))
+		}
+	}
+	return xproto.ChangePropertyChecked(
+		conn, xproto.PropModeReplace, root,
+		GetAtom("_NET_WORKAREA", conn),
+		xproto.AtomCardinal, 32, uint32(len(areas)*4), buf,
+	).Check()
+}
+
+// SetClientList sets _NET_CLIENT_LIST and _NET_CLIENT_LIST_STACKING,
+// windows are listed in order of mapping and in stacking order
+func SetClientList(clients, stacking []uint32, conn *xgb.Conn) error {
+	if err := setWindows("_NET_CLIENT_LIST", clients, conn); err != nil {
+		return err
+	}
+	return setWindows("_NET_CLIENT_LIST_STACKING", stacking, conn)
+}
+
+// SetActiveWindow sets _NET_ACTIVE_WINDOW, zero means there is no one
+func SetActiveWindow(wid uint32, conn *xgb.Conn) error {
+	return setWindows("_NET_ACTIVE_WINDOW", []uint32{wid}, conn)
+}
+
+func setWindows(name string, wids []uint32, conn *xgb.Conn) error {
+	root, err := getRoot(conn)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, len(wids)*4)
+	for i, wid := range wids {
+		xgb.Put32(buf[i*4:], wid)
+	}
+	return xproto.ChangePropertyChecked(
+		conn, xproto.PropModeReplace, root, GetAtom(name, conn),
+		xproto.AtomWindow, 32, uint32(len(wids)), buf,
+	).Check()
+}
+
 // SetDesktopNames sets desktops names
 func SetDesktopNames(names []string, conn *xgb.Conn) error {
 	nullterm := make([]byte, 0)
@@ -159,6 +285,166 @@ func GetWMName(wid uint32, conn *xgb.Conn) (string, error) {
 	return string(reply.Value), nil
 }
 
+// GetWMClass returns instance and class names
+// specified in WM_CLASS property of the window
+func GetWMClass(wid uint32, conn *xgb.Conn) (string, string, error) {
+	reply, err := xproto.GetProperty(
+		conn, false, xproto.Window(wid), xproto.AtomWmClass,
+		xproto.AtomString, 0, (1<<32)-1,
+	).Reply()
+
+	if err != nil {
+		return "", "", err
+	}
+	if reply.Format != 8 {
+		return "", "", errors.New("Error in getting property WM_CLASS")
+	}
+
+	parts := strings.Split(strings.TrimRight(string(reply.Value), "\x00"), "\x00")
+	if len(parts) < 2 {
+		return parts[0], "", nil
+	}
+	return parts[0], parts[1], nil
+}
+
+// SetWMDesktop sets _NET_WM_DESKTOP property of the window
+func SetWMDesktop(wid, n uint32, conn *xgb.Conn) error {
+	buf := make([]byte, 4)
+	xgb.Put32(buf, n-1)
+	return xproto.ChangePropertyChecked(
+		conn, xproto.PropModeReplace, xproto.Window(wid),
+		GetAtom("_NET_WM_DESKTOP", conn),
+		xproto.AtomCardinal, 32, 1, buf,
+	).Check()
+}
+
+// SetWMStateFullscreen adds _NET_WM_STATE_FULLSCREEN to _NET_WM_STATE
+// property of the window or removes it, other states are kept
+func SetWMStateFullscreen(wid uint32, enabled bool, conn *xgb.Conn) error {
+	fullscreen := GetAtom("_NET_WM_STATE_FULLSCREEN", conn)
+	atoms := SetAtom(getWMState(wid, conn), fullscreen, enabled)
+	buf := make([]byte, 4*len(atoms))
+	for i, atom := range atoms {
+		xgb.Put32(buf[4*i:], uint32(atom))
+	}
+	return xproto.ChangePropertyChecked(
+		conn, xproto.PropModeReplace, xproto.Window(wid),
+		GetAtom("_NET_WM_STATE", conn),
+		xproto.AtomAtom, 32, uint32(len(atoms)), buf,
+	).Check()
+}
+
+// HasWMStateFullscreen checks whether _NET_WM_STATE
+// property of the window includes _NET_WM_STATE_FULLSCREEN
+func HasWMStateFullscreen(wid uint32, conn *xgb.Conn) bool {
+	fullscreen := GetAtom("_NET_WM_STATE_FULLSCREEN", conn)
+	for _, atom := range getWMState(wid, conn) {
+		if atom == fullscreen {
+			return true
+		}
+	}
+	return false
+}
+
+// getWMState returns atoms listed in _NET_WM_STATE property of the window
+func getWMState(wid uint32, conn *xgb.Conn) []xproto.Atom {
+	reply, err := xproto.GetProperty(
+		conn, false, xproto.Window(wid), GetAtom("_NET_WM_STATE", conn),
+		xproto.AtomAtom, 0, (1<<32)-1,
+	).Reply()
+	if err != nil || reply.Format != 32 {
+		return nil
+	}
+
+	var atoms []xproto.Atom
+	for values := reply.Value; len(values) >= 4; values = values[4:] {
+		atoms = append(atoms, xproto.Atom(xgb.Get32(values)))
+	}
+	return atoms
+}
+
+// SetAtom adds the atom to the list if it is enabled and removes
+// all its occurrences otherwise, order of other atoms is kept
+func SetAtom(atoms []xproto.Atom, atom xproto.Atom, enabled bool) []xproto.Atom {
+	result := make([]xproto.Atom, 0, len(atoms)+1)
+	for _, a := range atoms {
+		if a != atom {
+			result = append(result, a)
+		}
+	}
+	if enabled {
+		result = append(result, atom)
+	}
+	return result
+}
+
+// GetWMDesktop returns zero-based index of the desktop
+// specified in _NET_WM_DESKTOP property of the window
+func GetWMDesktop(wid uint32, conn *xgb.Conn) (uint32, error) {
+	reply, err := xproto.GetProperty(
+		conn, false, xproto.Window(wid), GetAtom("_NET_WM_DESKTOP", conn),
+		xproto.AtomCardinal, 0, 1,
+	).Reply()
+
+	if err != nil {
+		return 0, err
+	}
+	if reply.Format != 32 || len(reply.Value) < 4 {
+		return 0, errors.New("Error in getting property _NET_WM_DESKTOP")
+	}
+	return xgb.Get32(reply.Value), nil
+}
+
+// GetWMPid returns identifier of the process
+// specified in _NET_WM_PID property of the window
+func GetWMPid(wid uint32, conn *xgb.Conn) (uint32, error) {
+	reply, err := xproto.GetProperty(
+		conn, false, xproto.Window(wid), GetAtom("_NET_WM_PID", conn),
+		xproto.AtomCardinal, 0, 1,
+	).Reply()
+
+	if err != nil {
+		return 0, err
+	}
+	if reply.Format != 32 || len(reply.Value) < 4 {
+		return 0, errors.New("Error in getting property _NET_WM_PID")
+	}
+	return xgb.Get32(reply.Value), nil
+}
+
+// IsNameAtom checks whether atom is one of the
+// properties holding window name, _NET_WM_NAME or WM_NAME
+func IsNameAtom(atom xproto.Atom, conn *xgb.Conn) bool {
+	return atom == GetAtom("_NET_WM_NAME", conn) ||
+		atom == xproto.AtomWmName
+}
+
+// GetWindowTypes returns names of the window types specified
+// in _NET_WM_WINDOW_TYPE property without the prefix in lower case
+func GetWindowTypes(wid uint32, conn *xgb.Conn) []string {
+	reply, err := xproto.GetProperty(
+		conn, false, xproto.Window(wid), GetAtom("_NET_WM_WINDOW_TYPE", conn),
+		xproto.AtomAtom, 0, (1<<32)-1,
+	).Reply()
+
+	if err != nil || reply.Format != 32 {
+		return nil
+	}
+
+	var types []string
+	for values := reply.Value; len(values) >= 4; values = values[4:] {
+		atom := xproto.Atom(xgb.Get32(values))
+		name, err := xproto.GetAtomName(conn, atom).Reply()
+		if err != nil {
+			continue
+		}
+		types = append(types, strings.ToLower(
+			strings.TrimPrefix(name.Name, "_NET_WM_WINDOW_TYPE_"),
+		))
+	}
+	return types
+}
+
 // IsDock checks whether the window is dock,
 // checking if it has _NET_WM_WINDOW_TYPE_DOCK defined
 func IsDock(wid uint32, conn *xgb.Conn) bool {
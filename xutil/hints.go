@@ -0,0 +1,136 @@
+// Package xutil provides high-level abstraction for the XGB functions
+package xutil
+
+import (
+	"errors"
+
+	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/xproto"
+)
+
+// Flags of WM_NORMAL_HINTS property
+const (
+	hintMinSize   = 1 << 4
+	hintMaxSize   = 1 << 5
+	hintResizeInc = 1 << 6
+	hintAspect    = 1 << 7
+	hintBaseSize  = 1 << 8
+)
+
+// SizeHints holds size constraints specified in WM_NORMAL_HINTS property.
+// Unspecified values are zero
+type SizeHints struct {
+	MinWidth   int
+	MinHeight  int
+	MaxWidth   int
+	MaxHeight  int
+	WidthInc   int
+	HeightInc  int
+	BaseWidth  int
+	BaseHeight int
+	MinAspect  float64
+	MaxAspect  float64
+}
+
+// IsFixed checks whether the window can't be resized,
+// having the same minimum and maximum sizes
+func (hints SizeHints) IsFixed() bool {
+	return hints.MinWidth > 0 && hints.MinHeight > 0 &&
+		hints.MinWidth == hints.MaxWidth && hints.MinHeight == hints.MaxHeight
+}
+
+// Constrain returns the largest size not exceeding the specified one,
+// which satisfies maximum size, aspect ratio and resize increments.
+// Minimum size isn't taken into account
+func (hints SizeHints) Constrain(width, height int) (int, int) {
+	if hints.MaxWidth > 0 && width > hints.MaxWidth {
+		width = hints.MaxWidth
+	}
+	if hints.MaxHeight > 0 && height > hints.MaxHeight {
+		height = hints.MaxHeight
+	}
+
+	if hints.MinAspect > 0 && hints.MaxAspect > 0 && height > 0 {
+		ratio := float64(width) / float64(height)
+		if ratio < hints.MinAspect {
+			height = int(float64(width) / hints.MinAspect)
+		} else if ratio > hints.MaxAspect {
+			width = int(float64(height) * hints.MaxAspect)
+		}
+	}
+
+	if hints.WidthInc > 1 && width > hints.BaseWidth {
+		width -= (width - hints.BaseWidth) % hints.WidthInc
+	}
+	if hints.HeightInc > 1 && height > hints.BaseHeight {
+		height -= (height - hints.BaseHeight) % hints.HeightInc
+	}
+	return width, height
+}
+
+// ParseSizeHints decodes value of WM_NORMAL_HINTS property
+func ParseSizeHints(value []byte) (SizeHints, error) {
+	var hints SizeHints
+	if len(value) < 15*4 {
+		return hints, errors.New("Error in getting property WM_NORMAL_HINTS")
+	}
+
+	field := func(i int) int {
+		return int(int32(xgb.Get32(value[i*4:])))
+	}
+	flags := field(0)
+	if flags&hintMinSize != 0 {
+		hints.MinWidth, hints.MinHeight = field(5), field(6)
+	}
+	if flags&hintMaxSize != 0 {
+		hints.MaxWidth, hints.MaxHeight = field(7), field(8)
+	}
+	if flags&hintResizeInc != 0 {
+		hints.WidthInc, hints.HeightInc = field(9), field(10)
+	}
+	if flags&hintAspect != 0 && field(12) > 0 && field(14) > 0 {
+		hints.MinAspect = float64(field(11)) / float64(field(12))
+		hints.MaxAspect = float64(field(13)) / float64(field(14))
+	}
+	if flags&hintBaseSize != 0 && len(value) >= 17*4 {
+		hints.BaseWidth, hints.BaseHeight = field(15), field(16)
+	}
+
+	// Base and minimum sizes substitute each other if one is missing
+	if flags&hintBaseSize == 0 {
+		hints.BaseWidth, hints.BaseHeight = hints.MinWidth, hints.MinHeight
+	} else if flags&hintMinSize == 0 {
+		hints.MinWidth, hints.MinHeight = hints.BaseWidth, hints.BaseHeight
+	}
+	return hints, nil
+}
+
+// GetSizeHints returns size constraints of the window
+func GetSizeHints(wid uint32, conn *xgb.Conn) (SizeHints, error) {
+	reply, err := xproto.GetProperty(
+		conn, false, xproto.Window(wid), xproto.AtomWmNormalHints,
+		xproto.AtomWmSizeHints, 0, 18,
+	).Reply()
+
+	if err != nil {
+		return SizeHints{}, err
+	}
+	if reply.Format != 32 {
+		return SizeHints{}, errors.New("Error in getting property WM_NORMAL_HINTS")
+	}
+	return ParseSizeHints(reply.Value)
+}
+
+// GetTransientFor returns identifier of the window specified
+// in WM_TRANSIENT_FOR property, zero if there is no such window
+func GetTransientFor(wid uint32, conn *xgb.Conn) uint32 {
+	reply, err := xproto.GetProperty(
+		conn, false, xproto.Window(wid), xproto.AtomWmTransientFor,
+		xproto.AtomWindow, 0, 1,
+	).Reply()
+
+	if err != nil || reply.Format != 32 || len(reply.Value) < 4 {
+		return 0
+	}
+	return xgb.Get32(reply.Value)
+}
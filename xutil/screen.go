@@ -14,13 +14,16 @@ type Screen struct {
 	width         int
 	height        int
 	xoffset       int
+	yoffset       int
 	paddingTop    int
 	paddingBottom int
+	paddingLeft   int
+	paddingRight  int
 }
 
 // NewScreen returns instance of Screen
 func NewScreen(width, height, xoffset, pTop, pBot int) Screen {
-	return Screen{width, height, xoffset, pTop, pBot}
+	return Screen{width, height, xoffset, 0, pTop, pBot, 0, 0}
 }
 
 // Width returns screen width
@@ -34,11 +37,16 @@ func (screen *Screen) Height() int {
 }
 
 // XOffset returns screen's ofsset on x-axis.
-// For primary monitor XOffset is always zero.
+// For primary monitor XOffset is usually zero.
 func (screen *Screen) XOffset() int {
 	return screen.xoffset
 }
 
+// YOffset returns screen's ofsset on y-axis
+func (screen *Screen) YOffset() int {
+	return screen.yoffset
+}
+
 // PaddingTop returns top padding of the screen.
 // One can use non-zero padding to left free space
 // needed for placing a status bar
@@ -53,12 +61,43 @@ func (screen *Screen) PaddingBottom() int {
 	return screen.paddingBottom
 }
 
-// MonitorsInfo holds information about connected screens.
-// Note that it's possible to connect only one external monitor.
+// PaddingLeft returns left padding of the screen,
+// which is reserved by docks placed along the edge
+func (screen *Screen) PaddingLeft() int {
+	return screen.paddingLeft
+}
+
+// PaddingRight returns right padding of the screen,
+// which is reserved by docks placed along the edge
+func (screen *Screen) PaddingRight() int {
+	return screen.paddingRight
+}
+
+// WorkArea returns position and size of the screen region
+// left for windows by paddings
+func (screen *Screen) WorkArea() (x, y, width, height int) {
+	x = screen.xoffset + screen.paddingLeft
+	y = screen.yoffset + screen.paddingTop
+	width = screen.width - screen.paddingLeft - screen.paddingRight
+	height = screen.height - screen.paddingTop - screen.paddingBottom
+	return x, y, width, height
+}
+
+// Contains checks whether the point belongs to the screen
+func (screen *Screen) Contains(x, y int) bool {
+	return x >= screen.xoffset && x < screen.xoffset+screen.width &&
+		y >= screen.yoffset && y < screen.yoffset+screen.height
+}
+
+// MonitorsInfo holds information about connected screens,
+// the first one is the primary screen
 type MonitorsInfo struct {
-	primary   Screen
-	secondary Screen
-	dual      bool
+	screens []Screen
+}
+
+// NewMonitorsInfo returns instance of MonitorsInfo
+func NewMonitorsInfo(screens ...Screen) MonitorsInfo {
+	return MonitorsInfo{screens}
 }
 
 // ReadMonitorsInfo returns information about connected monitors
@@ -70,32 +109,20 @@ func ReadMonitorsInfo(conn *xgb.Conn) (MonitorsInfo, error) {
 		return info, err
 	}
 
-	nscreen := len(r.ScreenInfo)
-	if nscreen < 1 {
+	if len(r.ScreenInfo) < 1 {
 		return info, errors.New("No screen info available")
 	}
 
-	if nscreen > 2 {
-		return info, errors.New("Only 2 monitors setup is supported")
-	}
-
-	info.primary = Screen{
-		int(r.ScreenInfo[0].Width),
-		int(r.ScreenInfo[0].Height),
-		int(r.ScreenInfo[0].XOrg),
-		config.PaddingTop(),
-		config.PaddingBottom(),
-	}
-
-	if nscreen == 2 {
-		info.dual = true
-		info.secondary = Screen{
-			int(r.ScreenInfo[1].Width),
-			int(r.ScreenInfo[1].Height),
-			int(r.ScreenInfo[1].XOrg),
-			0,
-			0,
+	for i, screen := range r.ScreenInfo {
+		var pTop, pBot int
+		if i == 0 {
+			pTop, pBot = config.PaddingTop(), config.PaddingBottom()
 		}
+		info.screens = append(info.screens, Screen{
+			int(screen.Width), int(screen.Height),
+			int(screen.XOrg), int(screen.YOrg),
+			pTop, pBot, 0, 0,
+		})
 	}
 
 	return info, nil
@@ -103,26 +130,92 @@ func ReadMonitorsInfo(conn *xgb.Conn) (MonitorsInfo, error) {
 
 // Primary returns information about primary screen
 func (m MonitorsInfo) Primary() Screen {
-	return m.primary
+	return m.screens[0]
+}
+
+// Screen returns information about the screen with the specified index
+func (m MonitorsInfo) Screen(i int) Screen {
+	return m.screens[i]
 }
 
-// Secondary returns information about secondary screen
-func (m MonitorsInfo) Secondary() Screen {
-	return m.secondary
+// Len returns the number of connected monitors
+func (m MonitorsInfo) Len() int {
+	return len(m.screens)
 }
 
-// InPrimaryRegion checks whether point with specified
-// x-coordinate is in the primary screen
-func (m MonitorsInfo) InPrimaryRegion(x int) bool {
-	return x <= m.primary.Width()
+// At returns index of the screen containing the point,
+// the primary screen is returned for points outside of all screens
+func (m MonitorsInfo) At(x, y int) int {
+	for i := range m.screens {
+		if m.screens[i].Contains(x, y) {
+			return i
+		}
+	}
+	return 0
 }
 
-// IsDualSetup returns true if external monitor is being used
-func (m MonitorsInfo) IsDualSetup() bool {
-	return m.dual
+// WithStruts returns monitors whose paddings are reserved by the
+// struts of docks. Paddings set in configuration are kept for the
+// edges of the monitors where no strut reserves space
+func (m MonitorsInfo) WithStruts(struts []Strut) MonitorsInfo {
+	var rootWidth, rootHeight int
+	for _, screen := range m.screens {
+		rootWidth = maxInt(rootWidth, screen.xoffset+screen.width)
+		rootHeight = maxInt(rootHeight, screen.yoffset+screen.height)
+	}
+
+	info := MonitorsInfo{make([]Screen, len(m.screens))}
+	for i, screen := range m.screens {
+		var top, bottom, left, right int
+		x, y := screen.xoffset, screen.yoffset
+		endx, endy := x+screen.width, y+screen.height
+		for _, strut := range struts {
+			if strut.Top > 0 && overlaps(strut.TopStartX, strut.TopEndX, x, endx) {
+				top = maxInt(top, strut.Top-y)
+			}
+			if strut.Bottom > 0 && overlaps(strut.BottomStartX, strut.BottomEndX, x, endx) {
+				bottom = maxInt(bottom, endy-(rootHeight-strut.Bottom))
+			}
+			if strut.Left > 0 && overlaps(strut.LeftStartY, strut.LeftEndY, y, endy) {
+				left = maxInt(left, strut.Left-x)
+			}
+			if strut.Right > 0 && overlaps(strut.RightStartY, strut.RightEndY, y, endy) {
+				right = maxInt(right, endx-(rootWidth-strut.Right))
+			}
+		}
+		if top > 0 {
+			screen.paddingTop = minInt(top, screen.height/2)
+		}
+		if bottom > 0 {
+			screen.paddingBottom = minInt(bottom, screen.height/2)
+		}
+		if left > 0 {
+			screen.paddingLeft = minInt(left, screen.width/2)
+		}
+		if right > 0 {
+			screen.paddingRight = minInt(right, screen.width/2)
+		}
+		info.screens[i] = screen
+	}
+	return info
+}
+
+// overlaps checks whether inclusive range [start, end]
+// of the strut overlaps with the half-open range [from, to)
+func overlaps(start, end, from, to int) bool {
+	return start < to && end >= from
 }
 
-// CommonWidth returns sum of primary and secondary monitors widths
-func (m MonitorsInfo) CommonWidth() int {
-	return m.primary.Width() + m.secondary.Width()
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
 }
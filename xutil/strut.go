@@ -0,0 +1,80 @@
+// Package xutil provides high-level abstraction for the XGB functions
+package xutil
+
+import (
+	"errors"
+	"math"
+
+	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/xproto"
+)
+
+// Strut holds space reserved by a dock along the edges of the root
+// window, as specified in _NET_WM_STRUT_PARTIAL. Start and end values
+// limit the reserved space along the edge, both are inclusive
+type Strut struct {
+	Left, Right, Top, Bottom int
+	LeftStartY, LeftEndY     int
+	RightStartY, RightEndY   int
+	TopStartX, TopEndX       int
+	BottomStartX, BottomEndX int
+}
+
+// IsEmpty checks whether the strut reserves no space
+func (strut Strut) IsEmpty() bool {
+	return strut.Left == 0 && strut.Right == 0 && strut.Top == 0 && strut.Bottom == 0
+}
+
+// ParseStrut decodes value of _NET_WM_STRUT_PARTIAL property,
+// or of _NET_WM_STRUT property reserving the whole edges
+func ParseStrut(value []byte) (Strut, error) {
+	field := func(i int) int {
+		return int(xgb.Get32(value[i*4:]))
+	}
+	switch {
+	case len(value) >= 12*4:
+		return Strut{
+			field(0), field(1), field(2), field(3),
+			field(4), field(5), field(6), field(7),
+			field(8), field(9), field(10), field(11),
+		}, nil
+	case len(value) >= 4*4:
+		return Strut{
+			Left: field(0), Right: field(1), Top: field(2), Bottom: field(3),
+			LeftEndY: math.MaxInt32, RightEndY: math.MaxInt32,
+			TopEndX: math.MaxInt32, BottomEndX: math.MaxInt32,
+		}, nil
+	}
+	return Strut{}, errors.New("Error in getting property _NET_WM_STRUT")
+}
+
+// GetStrut returns space reserved by the dock, _NET_WM_STRUT_PARTIAL
+// takes precedence over _NET_WM_STRUT property
+func GetStrut(wid uint32, conn *xgb.Conn) (Strut, error) {
+	var err error
+	for _, name := range []string{"_NET_WM_STRUT_PARTIAL", "_NET_WM_STRUT"} {
+		var reply *xproto.GetPropertyReply
+		reply, err = xproto.GetProperty(
+			conn, false, xproto.Window(wid), GetAtom(name, conn),
+			xproto.AtomCardinal, 0, 12,
+		).Reply()
+		if err != nil {
+			return Strut{}, err
+		}
+		if reply.Format != 32 {
+			continue
+		}
+		var strut Strut
+		if strut, err = ParseStrut(reply.Value); err == nil {
+			return strut, nil
+		}
+	}
+	return Strut{}, errors.New("Error in getting property _NET_WM_STRUT")
+}
+
+// IsStrutAtom checks whether atom is one of the
+// properties holding strut of the dock
+func IsStrutAtom(atom xproto.Atom, conn *xgb.Conn) bool {
+	return atom == GetAtom("_NET_WM_STRUT_PARTIAL", conn) ||
+		atom == GetAtom("_NET_WM_STRUT", conn)
+}
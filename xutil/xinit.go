@@ -2,9 +2,12 @@
 package xutil
 
 import (
+	"errors"
+	"time"
+
 	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/randr"
 	"github.com/BurntSushi/xgb/xproto"
-	"github.com/Zamony/wmwm/kbrd"
 )
 
 // Shortcut represents keys combination
@@ -31,68 +34,213 @@ func BecomeWM(conn *xgb.Conn, xroot xproto.ScreenInfo) error {
 	return changed.Check()
 }
 
+// SelectScreenChanges asks X to send RandR notifications
+// when monitors are connected, disconnected or reconfigured
+func SelectScreenChanges(conn *xgb.Conn, xroot xproto.ScreenInfo) error {
+	if err := randr.Init(conn); err != nil {
+		return err
+	}
+	return randr.SelectInputChecked(
+		conn, xroot.Root, randr.NotifyMaskScreenChange,
+	).Check()
+}
+
+// ReplaceTimeout limits waiting for the replaced window manager to exit
+const ReplaceTimeout = 5 * time.Second
+
+// AcquireWMSelection makes the window owner of WM_S0 manager selection
+// as described in ICCCM and announces it with MANAGER client message.
+// If another window manager holds the selection it is asked to exit
+// in case replace is set, otherwise an error is returned
+func AcquireWMSelection(wid uint32, replace bool, conn *xgb.Conn) error {
+	root, err := getRoot(conn)
+	if err != nil {
+		return err
+	}
+	selection := GetAtom("WM_S0", conn)
+	reply, err := xproto.GetSelectionOwner(conn, selection).Reply()
+	if err != nil {
+		return err
+	}
+
+	owner := reply.Owner
+	if owner != 0 && !replace {
+		return errors.New("Another window manager is running, use --replace")
+	}
+	if owner != 0 {
+		// Destruction of the owner window tells that the previous WM exited
+		err := ChangeWindowAttributesChecked(
+			conn, owner, xproto.CwEventMask,
+			[]uint32{xproto.EventMaskStructureNotify},
+		).Check()
+		if err != nil {
+			owner = 0
+		}
+	}
+
+	timestamp, err := getTimestamp(wid, conn)
+	if err != nil {
+		return err
+	}
+	err = xproto.SetSelectionOwnerChecked(
+		conn, xproto.Window(wid), selection, timestamp,
+	).Check()
+	if err != nil {
+		return err
+	}
+	reply, err = xproto.GetSelectionOwner(conn, selection).Reply()
+	if err != nil {
+		return err
+	}
+	if reply.Owner != xproto.Window(wid) {
+		return errors.New("Couldn't acquire WM_S0 selection")
+	}
+
+	if owner != 0 {
+		destroyed := waitForEvent(conn, ReplaceTimeout, func(event xgb.Event) bool {
+			e, ok := event.(xproto.DestroyNotifyEvent)
+			return ok && e.Window == owner
+		})
+		if !destroyed {
+			return errors.New("Previous window manager didn't exit")
+		}
+	}
+
+	manager := xproto.ClientMessageEvent{
+		Format: 32,
+		Window: root,
+		Type:   GetAtom("MANAGER", conn),
+		Data: xproto.ClientMessageDataUnionData32New([]uint32{
+			uint32(timestamp), uint32(selection), wid, 0, 0,
+		}),
+	}
+	return xproto.SendEventChecked(
+		conn, false, root, xproto.EventMaskStructureNotify,
+		string(manager.Bytes()),
+	).Check()
+}
+
+// IsWMSelection checks whether the atom is WM_S0 manager selection
+func IsWMSelection(atom xproto.Atom, conn *xgb.Conn) bool {
+	return atom == GetAtom("WM_S0", conn)
+}
+
+// getTimestamp obtains current server time appending
+// nothing to a property of the window, which has to
+// select PropertyChange events
+func getTimestamp(wid uint32, conn *xgb.Conn) (xproto.Timestamp, error) {
+	err := xproto.ChangePropertyChecked(
+		conn, xproto.PropModeAppend, xproto.Window(wid),
+		GetAtom("_NET_WM_NAME", conn), GetAtom("UTF8_STRING", conn),
+		8, 0, nil,
+	).Check()
+	if err != nil {
+		return 0, err
+	}
+
+	var timestamp xproto.Timestamp
+	notified := waitForEvent(conn, ReplaceTimeout, func(event xgb.Event) bool {
+		e, ok := event.(xproto.PropertyNotifyEvent)
+		if ok && e.Window == xproto.Window(wid) {
+			timestamp = e.Time
+		}
+		return ok && e.Window == xproto.Window(wid)
+	})
+	if !notified {
+		return 0, errors.New("Couldn't get X server time")
+	}
+	return timestamp, nil
+}
+
+// waitForEvent polls events discarding them until the matching one
+// arrives. It is used before the window manager starts handling events
+func waitForEvent(conn *xgb.Conn, timeout time.Duration, match func(xgb.Event) bool) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		event, err := conn.PollForEvent()
+		if event == nil && err == nil {
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+		if event != nil && match(event) {
+			return true
+		}
+	}
+	return false
+}
+
+// ModifiersMask selects modifiers which are taken into account
+// when matching shortcuts, Lock and NumLock (Mod2) are ignored
+const ModifiersMask = xproto.ModMaskShift | xproto.ModMaskControl |
+	xproto.ModMask1 | xproto.ModMask3 | xproto.ModMask4 | xproto.ModMask5
+
+// ignoredModifiers are combinations of Lock and NumLock (Mod2)
+// which shortcuts are grabbed with, so they work regardless of them
+var ignoredModifiers = []uint16{
+	0, xproto.ModMaskLock, xproto.ModMask2,
+	xproto.ModMaskLock | xproto.ModMask2,
+}
+
 // GrabShortcuts tells X that it should send
-// specified keys combinations directly to the WM
-func GrabShortcuts(conn *xgb.Conn, xroot xproto.ScreenInfo, keymap [256][]xproto.Keysym) error {
-	sym2code := make(map[xproto.Keysym]xproto.Keycode)
-	needed := map[xproto.Keysym]uint8{
-		kbrd.XK_BackSpace: 0, kbrd.XK_F1: 0,
-		kbrd.XK_F2: 0, kbrd.XK_F3: 0, kbrd.XK_F4: 0, kbrd.XK_F5: 0,
-		kbrd.XK_F6: 0, kbrd.XK_F7: 0, kbrd.XK_F8: 0, kbrd.XK_F9: 0,
-		kbrd.XK_Left: 0, kbrd.XK_Right: 0, kbrd.XK_Up: 0, kbrd.XK_Down: 0,
-		kbrd.XK_q: 0, kbrd.XK_Return: 0, kbrd.XK_grave: 0, kbrd.XK_t: 0,
-		kbrd.XK_f: 0, kbrd.XK_l: 0,
-	}
-	for i, syms := range keymap {
-		for _, sym := range syms {
-			if _, ok := needed[sym]; ok {
-				sym2code[sym] = xproto.Keycode(i)
+// specified keys combinations directly to the WM.
+// Previously grabbed combinations missing from the new ones
+// are released. If grabbing fails, the previous combinations
+// stay grabbed and the new ones are released
+func GrabShortcuts(conn *xgb.Conn, xroot xproto.ScreenInfo, shortcuts, previous []Shortcut) error {
+	old := make(map[Shortcut]bool)
+	for _, shortcut := range previous {
+		old[shortcut] = true
+	}
+
+	for i, shortcut := range shortcuts {
+		if err := grabShortcut(conn, xroot, shortcut); err != nil {
+			for _, grabbed := range shortcuts[:i+1] {
+				if !old[grabbed] {
+					ungrabShortcut(conn, xroot, grabbed)
+				}
 			}
+			return err
 		}
 	}
 
-	shortcuts := []Shortcut{
-		{xproto.ModMask4, sym2code[kbrd.XK_t]},
-		{xproto.ModMask4, sym2code[kbrd.XK_q]},
-		{xproto.ModMask4, sym2code[kbrd.XK_grave]},
-		{xproto.ModMask4, sym2code[kbrd.XK_f]},
-		{xproto.ModMask4, sym2code[kbrd.XK_l]},
-		{uint16(0), sym2code[kbrd.XK_F1]},
-		{uint16(0), sym2code[kbrd.XK_F2]}, {uint16(0), sym2code[kbrd.XK_F3]},
-		{uint16(0), sym2code[kbrd.XK_F4]}, {uint16(0), sym2code[kbrd.XK_F5]},
-		{uint16(0), sym2code[kbrd.XK_F6]}, {uint16(0), sym2code[kbrd.XK_F7]},
-		{uint16(0), sym2code[kbrd.XK_F8]}, {uint16(0), sym2code[kbrd.XK_F9]},
-		{xproto.ModMask4, sym2code[kbrd.XK_Left]},
-		{xproto.ModMask4, sym2code[kbrd.XK_Right]},
-		{xproto.ModMask4, sym2code[kbrd.XK_Up]},
-		{xproto.ModMask4, sym2code[kbrd.XK_Down]},
-		{xproto.ModMaskControl | xproto.ModMask4, sym2code[kbrd.XK_Left]},
-		{xproto.ModMaskControl | xproto.ModMask4, sym2code[kbrd.XK_Right]},
-		{xproto.ModMask4 | xproto.ModMask1, sym2code[kbrd.XK_Up]},
-		{xproto.ModMask4 | xproto.ModMask1, sym2code[kbrd.XK_Down]},
-		{xproto.ModMask4 | xproto.ModMask1, sym2code[kbrd.XK_Left]},
-		{xproto.ModMask4 | xproto.ModMask1, sym2code[kbrd.XK_Right]},
-		{xproto.ModMask4, sym2code[kbrd.XK_F1]},
-		{xproto.ModMask4, sym2code[kbrd.XK_F2]},
-		{xproto.ModMask4, sym2code[kbrd.XK_F3]},
-		{xproto.ModMask4, sym2code[kbrd.XK_F4]},
-		{xproto.ModMask4, sym2code[kbrd.XK_F5]},
-		{xproto.ModMask4, sym2code[kbrd.XK_F6]},
-		{xproto.ModMask4, sym2code[kbrd.XK_F7]},
-		{xproto.ModMask4, sym2code[kbrd.XK_F8]},
-		{xproto.ModMask4, sym2code[kbrd.XK_F9]},
+	current := make(map[Shortcut]bool)
+	for _, shortcut := range shortcuts {
+		current[shortcut] = true
 	}
+	for _, shortcut := range previous {
+		if !current[shortcut] {
+			ungrabShortcut(conn, xroot, shortcut)
+		}
+	}
+	return nil
+}
 
-	for _, shortcut := range shortcuts {
+// grabShortcut grabs keys combination
+// with any state of the ignored modifiers
+func grabShortcut(conn *xgb.Conn, xroot xproto.ScreenInfo, shortcut Shortcut) error {
+	for _, mods := range ignoredModifiers {
 		err := xproto.GrabKeyChecked(
-			conn, false, xroot.Root, shortcut.Modifiers,
+			conn, false, xroot.Root, shortcut.Modifiers|mods,
 			shortcut.Keycode, xproto.GrabModeAsync, xproto.GrabModeAsync,
 		).Check()
 		if err != nil {
 			return err
 		}
 	}
+	return nil
+}
 
+// ungrabShortcut releases keys combination
+// with any state of the ignored modifiers
+func ungrabShortcut(conn *xgb.Conn, xroot xproto.ScreenInfo, shortcut Shortcut) error {
+	for _, mods := range ignoredModifiers {
+		err := xproto.UngrabKeyChecked(
+			conn, shortcut.Keycode, xroot.Root, shortcut.Modifiers|mods,
+		).Check()
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
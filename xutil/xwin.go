@@ -68,12 +68,13 @@ func RemoveWindowBorder(wid uint32, conn *xgb.Conn) error {
 }
 
 // WatchWindowEvents subscribes window manager to the
-// MaskStructureNotify and MaskEnterWindow events
+// MaskStructureNotify, MaskEnterWindow and MaskPropertyChange events
 func WatchWindowEvents(wid uint32, conn *xgb.Conn) error {
 	return ChangeWindowAttributesChecked(
 		conn, xproto.Window(wid),
 		xproto.CwEventMask, []uint32{
-			xproto.EventMaskStructureNotify | xproto.EventMaskEnterWindow,
+			xproto.EventMaskStructureNotify | xproto.EventMaskEnterWindow |
+				xproto.EventMaskPropertyChange,
 		},
 	).Check()
 }
@@ -108,3 +109,110 @@ func AddPaddingToWindow(y, height, bwidth int, wid uint32, conn *xgb.Conn) error
 		[]uint32{uint32(y + bwidth), uint32(height - bwidth)},
 	).Check()
 }
+
+// MoveResizeWindow changes position and size of the window
+func MoveResizeWindow(x, y, width, height int, wid uint32, conn *xgb.Conn) error {
+	return ConfigureWindowChecked(
+		conn, xproto.Window(wid),
+		xproto.ConfigWindowX|xproto.ConfigWindowY|
+			xproto.ConfigWindowWidth|xproto.ConfigWindowHeight,
+		[]uint32{uint32(x), uint32(y), uint32(width), uint32(height)},
+	).Check()
+}
+
+// ConfigureFromRequest applies values of the configure request
+// selected by the mask to the window
+func ConfigureFromRequest(e xproto.ConfigureRequestEvent, mask uint16, conn *xgb.Conn) error {
+	var values []uint32
+	fields := []struct {
+		flag  uint16
+		value uint32
+	}{
+		{xproto.ConfigWindowX, uint32(e.X)},
+		{xproto.ConfigWindowY, uint32(e.Y)},
+		{xproto.ConfigWindowWidth, uint32(e.Width)},
+		{xproto.ConfigWindowHeight, uint32(e.Height)},
+		{xproto.ConfigWindowBorderWidth, uint32(e.BorderWidth)},
+		{xproto.ConfigWindowSibling, uint32(e.Sibling)},
+		{xproto.ConfigWindowStackMode, uint32(e.StackMode)},
+	}
+	for _, field := range fields {
+		if mask&field.flag != 0 {
+			values = append(values, field.value)
+		}
+	}
+	if len(values) < 1 {
+		return nil
+	}
+	return ConfigureWindowChecked(conn, e.Window, mask, values).Check()
+}
+
+// SendConfigureNotify sends synthetic ConfigureNotify event
+// telling the client its geometry
+func SendConfigureNotify(x, y, width, height int, wid uint32, conn *xgb.Conn) error {
+	ev := xproto.ConfigureNotifyEvent{
+		Event:  xproto.Window(wid),
+		Window: xproto.Window(wid),
+		X:      int16(x),
+		Y:      int16(y),
+		Width:  uint16(width),
+		Height: uint16(height),
+	}
+	return xproto.SendEventChecked(
+		conn, false, xproto.Window(wid), xproto.EventMaskStructureNotify,
+		string(ev.Bytes()),
+	).Check()
+}
+
+// GetGeometry returns position and size of the window
+func GetGeometry(wid uint32, conn *xgb.Conn) (x, y, width, height int, err error) {
+	geom, err := xproto.GetGeometry(conn, xproto.Drawable(wid)).Reply()
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+	return int(geom.X), int(geom.Y), int(geom.Width), int(geom.Height), nil
+}
+
+// RaiseWindow puts the window on top of the stack
+func RaiseWindow(wid uint32, conn *xgb.Conn) error {
+	return ConfigureWindowChecked(
+		conn, xproto.Window(wid),
+		xproto.ConfigWindowStackMode, []uint32{xproto.StackModeAbove},
+	).Check()
+}
+
+// IsViewable checks whether the window is mapped
+// and isn't override-redirect one
+func IsViewable(wid uint32, conn *xgb.Conn) bool {
+	attrs, err := xproto.GetWindowAttributes(conn, xproto.Window(wid)).Reply()
+	if err != nil {
+		return false
+	}
+	return Viewable(attrs)
+}
+
+// Viewable checks whether the window with the attributes
+// is mapped and isn't override-redirect one
+func Viewable(attrs *xproto.GetWindowAttributesReply) bool {
+	return !attrs.OverrideRedirect && attrs.MapState == xproto.MapStateViewable
+}
+
+// Exists checks whether the window exists
+// and isn't override-redirect one
+func Exists(wid uint32, conn *xgb.Conn) bool {
+	attrs, err := xproto.GetWindowAttributes(conn, xproto.Window(wid)).Reply()
+	return err == nil && !attrs.OverrideRedirect
+}
+
+// Children returns children of the window in stacking order
+func Children(wid uint32, conn *xgb.Conn) ([]uint32, error) {
+	tree, err := xproto.QueryTree(conn, xproto.Window(wid)).Reply()
+	if err != nil {
+		return nil, err
+	}
+	children := make([]uint32, len(tree.Children))
+	for i, child := range tree.Children {
+		children[i] = uint32(child)
+	}
+	return children, nil
+}